@@ -12,10 +12,16 @@ import (
 )
 
 var (
-	apiKey   string
-	baseURL  string
-	model    string
-	forceAdd bool
+	apiKey      string
+	keyCmd      string
+	keyFile     string
+	baseURL     string
+	model       string
+	apiProtocol string
+	authMode    string
+	keyExpires  string
+	keyOwner    string
+	forceAdd    bool
 )
 
 var addCmd = &cobra.Command{
@@ -27,8 +33,24 @@ var addCmd = &cobra.Command{
 预置供应商只需提供 --key:
   ccm add doubao --key "sk-xxx"
 
+API Key 也可以从环境变量、命令或文件读取，不写入配置文件:
+  ccm add doubao --key '${DOUBAO_KEY}'
+  ccm add doubao --key-cmd "pass show doubao"
+  ccm add doubao --key-file ~/.secrets/doubao
+
+可记录密钥的过期时间和负责人，临近过期时 ccm list、ccm run 会提醒:
+  ccm add doubao --key "sk-xxx" --expires 2026-12-31 --owner alice
+
 自定义供应商需要完整配置:
-  ccm add custom --key "xxx" --url "https://..." --model "xxx"`,
+  ccm add custom --key "xxx" --url "https://..." --model "xxx"
+
+OpenAI 兼容接口 (/chat/completions) 需指定协议，并通过 'ccm serve' 网关使用:
+  ccm add custom --key "xxx" --url "https://.../v1" --model "xxx" --protocol openai
+
+认证方式默认为 Authorization: Bearer，可用 --auth 指定:
+  ccm add custom ... --auth x-api-key         x-api-key 请求头 (ANTHROPIC_API_KEY)
+  ccm add custom ... --auth header:X-Token    自定义请求头
+  ccm add custom ... --auth query:key         URL 查询参数 (需经 'ccm serve' 网关)`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -38,10 +60,36 @@ var addCmd = &cobra.Command{
 		cyan := color.New(color.FgCyan).SprintFunc()
 		gray := color.New(color.FgHiBlack).SprintFunc()
 
-		if apiKey == "" {
-			fmt.Fprintln(os.Stderr, red("错误: 必须提供 --key 参数"))
+		switch countNonEmpty(apiKey, keyCmd, keyFile) {
+		case 0:
+			fmt.Fprintln(os.Stderr, red("错误: 必须提供 --key、--key-cmd 或 --key-file 参数"))
 			fmt.Fprintln(os.Stderr, "用法: ccm add <name> --key \"your-api-key\"")
 			os.Exit(1)
+		case 1:
+		default:
+			fmt.Fprintf(os.Stderr, "%s --key、--key-cmd 和 --key-file 只能指定一个\n", red("错误:"))
+			os.Exit(1)
+		}
+
+		if !validProtocol(apiProtocol) {
+			fmt.Fprintf(os.Stderr, "%s 不支持的协议: %s (可选: anthropic, openai)\n", red("错误:"), apiProtocol)
+			os.Exit(1)
+		}
+
+		expiresAt, err := parseKeyExpiry(keyExpires)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		var auth provider.AuthMode
+		var authParam string
+		if authMode != "" {
+			var err error
+			if auth, authParam, err = provider.ParseAuth(authMode); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
 		}
 
 		var p provider.Provider
@@ -72,6 +120,14 @@ var addCmd = &cobra.Command{
 				Model:       model,
 			}
 		}
+		p.APIKeyCmd, p.APIKeyFile = keyCmd, keyFile
+		p.KeyMeta = provider.KeyMeta{CreatedAt: keyCreatedAt(), ExpiresAt: expiresAt, Owner: keyOwner}
+		if apiProtocol != "" {
+			p.Protocol = provider.Protocol(apiProtocol)
+		}
+		if auth != "" {
+			p.Auth, p.AuthParam = auth, authParam
+		}
 
 		// 检查是否已存在配置
 		cfg, _ := config.Load()
@@ -83,6 +139,10 @@ var addCmd = &cobra.Command{
 			// 确认逻辑在 init 中处理
 		}
 
+		// 启用密钥库时需要解锁才能加密保存新的 API Key
+		if cfg != nil {
+			ensureUnlocked(cfg)
+		}
 		if err := config.AddProvider(p); err != nil {
 			fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
 			os.Exit(1)
@@ -91,6 +151,13 @@ var addCmd = &cobra.Command{
 		fmt.Printf("%s 已配置供应商: %s\n", green("✓"), p.DisplayName)
 		fmt.Printf("  API URL: %s\n", p.BaseURL)
 		fmt.Printf("  模型: %s\n", p.Model)
+		fmt.Printf("  协议: %s\n", p.EffectiveProtocol())
+		fmt.Printf("  认证: %s\n", p.AuthLabel())
+		if cfg, err := config.Load(); err == nil {
+			if src := config.FindKeySource(cfg, name); src != nil {
+				fmt.Printf("  密钥来源: %s\n", config.DescribeKeySource(src))
+			}
+		}
 		fmt.Println()
 		fmt.Println(cyan("下一步操作:"))
 		fmt.Printf("  %s             # 测试连接\n", gray(fmt.Sprintf("ccm test %s", name)))
@@ -99,10 +166,25 @@ var addCmd = &cobra.Command{
 	},
 }
 
+// validProtocol 检查协议名称是否合法（空值表示沿用默认）
+func validProtocol(proto string) bool {
+	switch provider.Protocol(proto) {
+	case "", provider.ProtocolAnthropic, provider.ProtocolOpenAI:
+		return true
+	}
+	return false
+}
+
 func init() {
-	addCmd.Flags().StringVarP(&apiKey, "key", "k", "", "API 密钥 (必填)")
+	addCmd.Flags().StringVarP(&apiKey, "key", "k", "", "API 密钥 (可用 ${ENV} 引用环境变量)")
+	addCmd.Flags().StringVar(&keyCmd, "key-cmd", "", "输出 API 密钥的命令，取第一行 (如 \"pass show doubao\")")
+	addCmd.Flags().StringVar(&keyFile, "key-file", "", "保存 API 密钥的文件")
 	addCmd.Flags().StringVarP(&baseURL, "url", "u", "", "API URL (自定义供应商必填)")
 	addCmd.Flags().StringVarP(&model, "model", "m", "", "模型名称 (自定义供应商必填)")
+	addCmd.Flags().StringVar(&apiProtocol, "protocol", "", "API 协议: anthropic 或 openai (默认沿用预置值)")
+	addCmd.Flags().StringVar(&authMode, "auth", "", "认证方式: bearer, x-api-key, header:<名称>, query:<参数名> (默认 bearer)")
+	addCmd.Flags().StringVar(&keyExpires, "expires", "", "API 密钥的过期时间 (2026-12-31 或 90d)")
+	addCmd.Flags().StringVar(&keyOwner, "owner", "", "API 密钥的负责人")
 	addCmd.Flags().BoolVarP(&forceAdd, "force", "f", false, "强制覆盖已有配置，不询问")
 	rootCmd.AddCommand(addCmd)
 }
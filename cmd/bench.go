@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/probe"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchRequests    int
+	benchConcurrency int
+	benchMaxTokens   int
+	benchJSON        bool
+)
+
+// benchRow 基准测试结果的 JSON 输出格式
+type benchRow struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	TTFTMs       int64   `json:"ttft_ms"`
+	TokensPerSec float64 `json:"tokens_per_sec"`
+	P50Ms        int64   `json:"p50_ms"`
+	P95Ms        int64   `json:"p95_ms"`
+	LastError    string  `json:"last_error,omitempty"`
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [name...]",
+	Short: "测试供应商的延迟和吞吐",
+	Long: `测试供应商的延迟和吞吐
+
+对每个供应商并发发送流式请求，统计首字延迟 (TTFT)、生成速度 (tokens/s)、
+完整请求耗时的 p50/p95 以及错误率。不指定供应商时测试所有已配置的供应商。
+
+示例:
+  ccm bench                         测试所有已配置的供应商
+  ccm bench doubao deepseek         只测试指定供应商
+  ccm bench -n 20 -c 5              每个供应商 20 个请求，5 个并发
+  ccm bench --json                  以 JSON 格式输出`,
+	Run: func(cmd *cobra.Command, args []string) {
+		red := color.New(color.FgRed).SprintFunc()
+		cyan := color.New(color.FgCyan).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		ensureUnlocked(cfg)
+
+		names := args
+		if len(names) == 0 {
+			for _, name := range cfg.SortedNames() {
+				if config.FindKeySource(cfg, name) != nil {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			fmt.Println("没有已配置的供应商")
+			fmt.Println("请先使用 'ccm add <name> --key \"...\"' 配置供应商")
+			return
+		}
+
+		opts := probe.BenchOptions{
+			Requests:    benchRequests,
+			Concurrency: benchConcurrency,
+			MaxTokens:   benchMaxTokens,
+		}
+
+		var results []probe.BenchResult
+		for _, name := range names {
+			p, ok := cfg.Providers[name]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
+				os.Exit(1)
+			}
+			apiKey, _, err := config.ResolveAPIKey(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 获取供应商 '%s' 的 API Key 失败: %v\n", red("错误:"), name, err)
+				os.Exit(1)
+			}
+
+			if !benchJSON {
+				fmt.Fprintf(os.Stderr, "%s %s (%d 个请求, %d 并发)...\n", gray("正在测试"), cyan(name), opts.Requests, opts.Concurrency)
+			}
+			results = append(results, probe.Bench(context.Background(), p, apiKey, opts))
+		}
+
+		if benchJSON {
+			printBenchJSON(results)
+			return
+		}
+		printBenchTable(results)
+	},
+}
+
+func printBenchJSON(results []probe.BenchResult) {
+	rows := make([]benchRow, 0, len(results))
+	for _, r := range results {
+		row := benchRow{
+			Provider:     r.Provider,
+			Model:        r.Model,
+			Requests:     r.Requests,
+			Errors:       r.Errors,
+			ErrorRate:    r.ErrorRate(),
+			TTFTMs:       r.TTFT.Milliseconds(),
+			TokensPerSec: r.TokensPerSec,
+			P50Ms:        r.P50.Milliseconds(),
+			P95Ms:        r.P95.Milliseconds(),
+		}
+		if r.LastError != nil {
+			row.LastError = r.LastError.Error()
+		}
+		rows = append(rows, row)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(rows)
+}
+
+func printBenchTable(results []probe.BenchResult) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	fmt.Println()
+	fmt.Printf("  %s %s %s %s %s %s %s\n",
+		padRight("供应商", 12), padRight("错误率", 8), padRight("首字延迟", 10),
+		padRight("速度", 12), padRight("P50", 10), padRight("P95", 10), "模型")
+
+	for _, r := range results {
+		rate := fmt.Sprintf("%.0f%%", r.ErrorRate()*100)
+		if r.Errors > 0 {
+			rate = red(padRight(rate, 8))
+		} else {
+			rate = green(padRight(rate, 8))
+		}
+		fmt.Printf("  %s %s %s %s %s %s %s\n",
+			cyan(padRight(r.Provider, 12)),
+			rate,
+			padRight(formatMs(r.TTFT), 10),
+			padRight(fmt.Sprintf("%.1f tok/s", r.TokensPerSec), 12),
+			padRight(formatMs(r.P50), 10),
+			padRight(formatMs(r.P95), 10),
+			gray(r.Model),
+		)
+		if r.LastError != nil {
+			fmt.Printf("    %s %s\n", gray("└"), red(r.LastError.Error()))
+		}
+	}
+	fmt.Println()
+}
+
+// formatMs 以毫秒显示耗时，无数据时显示 "-"
+func formatMs(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+func init() {
+	benchCmd.Flags().IntVarP(&benchRequests, "requests", "n", 5, "每个供应商的请求数")
+	benchCmd.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 5, "并发请求数")
+	benchCmd.Flags().IntVar(&benchMaxTokens, "max-tokens", 256, "每个请求的最大输出 token")
+	benchCmd.Flags().BoolVar(&benchJSON, "json", false, "以 JSON 格式输出")
+	rootCmd.AddCommand(benchCmd)
+}
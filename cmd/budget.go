@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ccm/internal/config"
+	"ccm/internal/usage"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	budgetDaily    float64
+	budgetMonthly  float64
+	budgetEnforce  bool
+	budgetClear    bool
+	budgetCurrency string
+	budgetRates    []string
+)
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget [name]",
+	Short: "设置供应商的费用预算",
+	Long: `设置供应商的费用预算
+
+按 'ccm usage' 的方式统计供应商当天和当月的费用。超出预算时，'ccm run' 会给出警告；
+使用 --enforce 后则拒绝启动。预算金额以统一货币表示 (默认 USD)，
+以其他货币计价的供应商按汇率换算 (内置 1 USD = 7.2 CNY，可用 --rate 覆盖)。
+
+不带参数时显示所有预算及当前费用。
+
+示例:
+  ccm budget                              查看预算和费用
+  ccm budget deepseek --daily 5 --monthly 100   设置日/月预算
+  ccm budget deepseek --enforce           超出预算时拒绝启动
+  ccm budget deepseek --clear             删除预算
+  ccm budget --currency CNY               以人民币统计费用和预算
+  ccm budget --rate CNY=7.1               设置汇率 (1 USD 可兑换的数量)`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		changed := false
+
+		// 汇率
+		for _, kv := range budgetRates {
+			currency, value, ok := strings.Cut(kv, "=")
+			rate, err := strconv.ParseFloat(value, 64)
+			if !ok || err != nil || rate <= 0 {
+				fmt.Fprintf(os.Stderr, "%s 无效的汇率 '%s'，格式: CNY=7.2\n", red("错误:"), kv)
+				os.Exit(1)
+			}
+			if cfg.Rates == nil {
+				cfg.Rates = map[string]float64{}
+			}
+			cfg.Rates[strings.ToUpper(currency)] = rate
+			fmt.Printf("%s 汇率: 1 USD = %g %s\n", green("✓"), rate, strings.ToUpper(currency))
+			changed = true
+		}
+
+		// 统计货币，已有预算按汇率换算
+		if budgetCurrency != "" {
+			to := strings.ToUpper(budgetCurrency)
+			from := cfg.BudgetCurrency()
+			if _, ok := cfg.Rate(to); !ok {
+				fmt.Fprintf(os.Stderr, "%s 未配置 %s 的汇率，请先使用 --rate %s=<汇率>\n", red("错误:"), to, to)
+				os.Exit(1)
+			}
+			for name, b := range cfg.Budgets {
+				daily, err := cfg.Convert(b.Daily, from, to)
+				if err == nil {
+					b.Monthly, err = cfg.Convert(b.Monthly, from, to)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s 换算 %s 的预算失败: %v\n", red("错误:"), name, err)
+					os.Exit(1)
+				}
+				b.Daily = daily
+				cfg.Budgets[name] = b
+			}
+			cfg.Currency = to
+			fmt.Printf("%s 统计货币: %s\n", green("✓"), to)
+			changed = true
+		}
+
+		// 供应商预算
+		if len(args) > 0 {
+			name := args[0]
+			if budgetClear {
+				delete(cfg.Budgets, name)
+				fmt.Printf("%s 已删除 %s 的预算\n", green("✓"), name)
+				changed = true
+			} else if cmd.Flags().Changed("daily") || cmd.Flags().Changed("monthly") || cmd.Flags().Changed("enforce") {
+				if _, ok := cfg.Providers[name]; !ok {
+					fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
+					os.Exit(1)
+				}
+				b := cfg.Budgets[name]
+				if cmd.Flags().Changed("daily") {
+					b.Daily = budgetDaily
+				}
+				if cmd.Flags().Changed("monthly") {
+					b.Monthly = budgetMonthly
+				}
+				if cmd.Flags().Changed("enforce") {
+					b.Enforce = budgetEnforce
+				}
+				if cfg.Budgets == nil {
+					cfg.Budgets = map[string]config.Budget{}
+				}
+				cfg.Budgets[name] = b
+				fmt.Printf("%s 已设置 %s 的预算: %s\n", green("✓"), name, describeBudget(b, cfg.BudgetCurrency()))
+				changed = true
+			}
+		}
+
+		if changed {
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		printBudgets(cfg, args)
+	},
+}
+
+// printBudgets 显示预算和当前费用
+func printBudgets(cfg *config.Config, names []string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	if len(names) == 0 {
+		for name := range cfg.Budgets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		fmt.Println("尚未设置预算")
+		fmt.Println("使用 'ccm budget <name> --daily 5 --monthly 100' 设置")
+		return
+	}
+
+	currency := cfg.BudgetCurrency()
+	fmt.Println()
+	fmt.Printf("  %s %s %s %s\n", padRight("供应商", 12), padRight("今日", 22), padRight("本月", 22), "超出时")
+	for _, name := range names {
+		b := cfg.Budgets[name]
+		spend, err := usage.ProviderSpend(cfg, name)
+		if err != nil {
+			fmt.Printf("  %s %s\n", padRight(name, 12), red(err.Error()))
+			continue
+		}
+		action := "警告"
+		if b.Enforce {
+			action = "拒绝启动"
+		}
+		fmt.Printf("  %s %s %s %s\n",
+			cyan(padRight(name, 12)),
+			padRight(budgetUsage(spend.Today, b.Daily, currency), 22),
+			padRight(budgetUsage(spend.Month, b.Monthly, currency), 22),
+			gray(action),
+		)
+	}
+	fmt.Println()
+}
+
+// budgetUsage 显示 "已用 / 上限"
+func budgetUsage(spent, limit float64, currency string) string {
+	if limit <= 0 {
+		return formatMoney(spent, currency) + " / -"
+	}
+	return formatMoney(spent, currency) + " / " + formatMoney(limit, currency)
+}
+
+// describeBudget 返回预算的简短描述
+func describeBudget(b config.Budget, currency string) string {
+	var parts []string
+	if b.Daily > 0 {
+		parts = append(parts, "每日 "+formatMoney(b.Daily, currency))
+	}
+	if b.Monthly > 0 {
+		parts = append(parts, "每月 "+formatMoney(b.Monthly, currency))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "不限额")
+	}
+	if b.Enforce {
+		parts = append(parts, "超出时拒绝启动")
+	} else {
+		parts = append(parts, "超出时警告")
+	}
+	return strings.Join(parts, "，")
+}
+
+// checkBudget 检查供应商本日和本月的费用，超出预算时警告或拒绝启动
+func checkBudget(cfg *config.Config, name string) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	b, ok := cfg.Budgets[name]
+	if !ok || (b.Daily <= 0 && b.Monthly <= 0) {
+		return
+	}
+
+	spend, err := usage.ProviderSpend(cfg, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 统计费用失败，跳过预算检查: %v\n", yellow("警告:"), err)
+		return
+	}
+
+	currency := cfg.BudgetCurrency()
+	var exceeded []string
+	if b.Daily > 0 && spend.Today >= b.Daily {
+		exceeded = append(exceeded, fmt.Sprintf("今日费用 %s 已达到日预算 %s", formatMoney(spend.Today, currency), formatMoney(b.Daily, currency)))
+	}
+	if b.Monthly > 0 && spend.Month >= b.Monthly {
+		exceeded = append(exceeded, fmt.Sprintf("本月费用 %s 已达到月预算 %s", formatMoney(spend.Month, currency), formatMoney(b.Monthly, currency)))
+	}
+	if len(exceeded) == 0 {
+		return
+	}
+
+	if b.Enforce {
+		for _, msg := range exceeded {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", red("错误:"), name, msg)
+		}
+		fmt.Fprintf(os.Stderr, "调整预算: %s\n", cyan(fmt.Sprintf("ccm budget %s --daily <金额> --monthly <金额>", name)))
+		os.Exit(1)
+	}
+	for _, msg := range exceeded {
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", yellow("警告:"), name, msg)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func init() {
+	budgetCmd.Flags().Float64Var(&budgetDaily, "daily", 0, "每日费用上限 (0 表示不限)")
+	budgetCmd.Flags().Float64Var(&budgetMonthly, "monthly", 0, "每月费用上限 (0 表示不限)")
+	budgetCmd.Flags().BoolVar(&budgetEnforce, "enforce", false, "超出预算时拒绝启动 (--enforce=false 改为仅警告)")
+	budgetCmd.Flags().BoolVar(&budgetClear, "clear", false, "删除供应商的预算")
+	budgetCmd.Flags().StringVar(&budgetCurrency, "currency", "", "费用统计和预算使用的货币 (如 USD、CNY)")
+	budgetCmd.Flags().StringArrayVar(&budgetRates, "rate", nil, "汇率，1 USD 可兑换的数量 (如 CNY=7.2，可重复)")
+	rootCmd.AddCommand(budgetCmd)
+}
@@ -18,6 +18,7 @@ var defaultCmd = &cobra.Command{
 
 不带参数时显示当前默认供应商，带参数时设置默认供应商。
 设置默认供应商后，运行 'ccm run' 时可以不指定供应商名称。
+项目目录中的 .ccm.yaml 优先于默认供应商。
 
 示例:
   ccm default           显示当前默认供应商
@@ -39,6 +40,12 @@ var defaultCmd = &cobra.Command{
 			} else {
 				fmt.Printf("当前默认供应商: %s\n", green(defaultProvider))
 			}
+			// 项目配置优先于默认供应商
+			if dir, err := os.Getwd(); err == nil {
+				if project, err := config.FindProject(dir); err == nil && project != nil {
+					fmt.Printf("当前目录使用项目供应商: %s (%s)\n", green(project.Provider), shortenHome(project.Path))
+				}
+			}
 			return
 		}
 
@@ -2,18 +2,41 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"ccm/internal/config"
+	"ccm/internal/provider"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 var (
-	newAPIKey  string
-	newBaseURL string
-	newModel   string
+	newAPIKey   string
+	newKeyCmd   string
+	newKeyFile  string
+	newBaseURL  string
+	newModel    string
+	newProtocol string
+	newAuth     string
+	newExpires  string
+	newOwner    string
+
+	newPriceInput      float64
+	newPriceOutput     float64
+	newPriceCacheRead  float64
+	newPriceCacheWrite float64
+	newCurrency        string
+	newPriceModel      string
+
+	newModels []string
+	newRoles  []string
+
+	newEnv     []string
+	newHeaders []string
 )
 
 var editCmd = &cobra.Command{
@@ -24,8 +47,25 @@ var editCmd = &cobra.Command{
 
 示例:
   ccm edit doubao --key "new-key"          更新 API Key
+  ccm edit doubao --key '${DOUBAO_KEY}'    从环境变量读取 API Key
+  ccm edit doubao --key-cmd "pass show doubao"
+                                           从命令输出 (第一行) 读取 API Key
+  ccm edit doubao --key-file ~/.secrets/doubao
+                                           从文件读取 API Key
+  ccm edit doubao --expires 2026-12-31     记录 API Key 的过期时间 (为空时清除)
+  ccm edit doubao --owner alice            记录 API Key 的负责人
   ccm edit doubao --url "https://..."      更新 API URL
   ccm edit doubao --model "xxx"            更新模型
+  ccm edit custom --protocol openai        更新 API 协议
+  ccm edit custom --auth x-api-key         更新认证方式 (bearer/x-api-key/header:<名称>/query:<参数名>)
+  ccm edit deepseek --models deepseek-chat,deepseek-reasoner
+                                           设置可用模型列表
+  ccm edit deepseek --role haiku=deepseek-chat
+                                           后台任务使用的模型 (opus/sonnet/haiku/small_fast)
+  ccm edit kimi --price-input 4 --price-output 16 --currency CNY
+                                           设置单价 (每百万 token)
+  ccm edit custom --env DISABLE_TELEMETRY=1  启动时额外设置的环境变量 (KEY= 删除)
+  ccm edit custom --header "X-Tenant: acme"  请求供应商时附加的请求头 (值为空时删除)
   ccm edit doubao -k "xxx" -u "..." -m "..."  一次性更新多个`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -50,8 +90,49 @@ var editCmd = &cobra.Command{
 
 		// 更新字段
 		updated := false
+		// API Key 只保留一个来源
+		if n := countNonEmpty(newAPIKey, newKeyCmd, newKeyFile); n > 1 {
+			fmt.Fprintf(os.Stderr, "%s --key、--key-cmd 和 --key-file 只能指定一个\n", red("错误:"))
+			os.Exit(1)
+		}
+		// 配置了多个密钥时 api_key 不生效
+		if countNonEmpty(newAPIKey, newKeyCmd, newKeyFile) > 0 && len(p.Keys) > 0 {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 配置了多个密钥，请使用 'ccm keys %s --add <标签> ...' 管理\n", red("错误:"), name, name)
+			os.Exit(1)
+		}
+		if (cmd.Flags().Changed("expires") || cmd.Flags().Changed("owner")) && len(p.Keys) > 0 {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 配置了多个密钥，请使用 'ccm keys %s --add <标签> --expires ...' 设置\n", red("错误:"), name, name)
+			os.Exit(1)
+		}
+		if countNonEmpty(newAPIKey, newKeyCmd, newKeyFile) > 0 {
+			// 旧密钥的过期时间不适用于新密钥
+			p.KeyMeta.CreatedAt, p.KeyMeta.ExpiresAt = keyCreatedAt(), time.Time{}
+		}
 		if newAPIKey != "" {
-			p.APIKey = newAPIKey
+			// 启用密钥库时需要解锁才能加密保存
+			ensureUnlocked(cfg)
+			p.APIKey, p.APIKeyCmd, p.APIKeyFile = newAPIKey, "", ""
+			updated = true
+		}
+		if newKeyCmd != "" {
+			p.APIKey, p.APIKeyCmd, p.APIKeyFile = "", newKeyCmd, ""
+			updated = true
+		}
+		if newKeyFile != "" {
+			p.APIKey, p.APIKeyCmd, p.APIKeyFile = "", "", newKeyFile
+			updated = true
+		}
+		if cmd.Flags().Changed("expires") {
+			expiresAt, err := parseKeyExpiry(newExpires)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			p.KeyMeta.ExpiresAt = expiresAt
+			updated = true
+		}
+		if cmd.Flags().Changed("owner") {
+			p.KeyMeta.Owner = newOwner
 			updated = true
 		}
 		if newBaseURL != "" {
@@ -62,9 +143,77 @@ var editCmd = &cobra.Command{
 			p.Model = newModel
 			updated = true
 		}
+		if newProtocol != "" {
+			if !validProtocol(newProtocol) {
+				fmt.Fprintf(os.Stderr, "%s 不支持的协议: %s (可选: anthropic, openai)\n", red("错误:"), newProtocol)
+				os.Exit(1)
+			}
+			p.Protocol = provider.Protocol(newProtocol)
+			updated = true
+		}
+		if newAuth != "" {
+			auth, param, err := provider.ParseAuth(newAuth)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			p.Auth, p.AuthParam = auth, param
+			updated = true
+		}
+
+		if cmd.Flags().Changed("models") {
+			p.Models = newModels
+			updated = true
+		}
+		if len(newRoles) > 0 {
+			// 首次修改时以预置映射为基础
+			roles := p.EffectiveRoles()
+			for _, kv := range newRoles {
+				role, m, ok := strings.Cut(kv, "=")
+				if !ok || !roles.Set(strings.ToLower(role), m) {
+					fmt.Fprintf(os.Stderr, "%s 无效的角色映射 '%s'，格式: <角色>=<模型>，角色可选: %s\n",
+						red("错误:"), kv, strings.Join(provider.RoleNames, ", "))
+					os.Exit(1)
+				}
+			}
+			p.Roles = &roles
+			updated = true
+		}
+
+		if len(newEnv) > 0 {
+			env, err := parseEnvOverrides(newEnv)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			p.Env = mergeEntries(p.Env, env)
+			updated = true
+		}
+		if len(newHeaders) > 0 {
+			headers, err := parseHeaders(newHeaders)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			p.Headers = mergeEntries(p.Headers, headers)
+			updated = true
+		}
+
+		pricing, pricingChanged := editPricing(cmd, p)
+		if pricingChanged {
+			if newPriceModel != "" {
+				if p.ModelPrices == nil {
+					p.ModelPrices = make(map[string]provider.Pricing)
+				}
+				p.ModelPrices[newPriceModel] = *pricing
+			} else {
+				p.Pricing = pricing
+			}
+			updated = true
+		}
 
 		if !updated {
-			fmt.Fprintf(os.Stderr, "%s 请指定要更新的字段 (--key, --url, --model)\n", red("错误:"))
+			fmt.Fprintf(os.Stderr, "%s 请指定要更新的字段 (--key, --expires, --owner, --url, --model, --protocol, --auth, --models, --role, --price-*, --env, --header)\n", red("错误:"))
 			os.Exit(1)
 		}
 
@@ -76,8 +225,11 @@ var editCmd = &cobra.Command{
 		}
 
 		fmt.Printf("%s 已更新供应商: %s\n", green("✓"), p.DisplayName)
-		if newAPIKey != "" {
-			fmt.Printf("  API Key:    ********\n")
+		if newAPIKey != "" || newKeyCmd != "" || newKeyFile != "" {
+			fmt.Printf("  API Key:    %s\n", config.DescribeKeySource(config.FindKeySource(cfg, name)))
+		}
+		if cmd.Flags().Changed("expires") || cmd.Flags().Changed("owner") {
+			fmt.Printf("  密钥信息:   %s\n", formatKeyMeta(p.KeyMeta))
 		}
 		if newBaseURL != "" {
 			fmt.Printf("  API URL:    %s\n", p.BaseURL)
@@ -85,14 +237,180 @@ var editCmd = &cobra.Command{
 		if newModel != "" {
 			fmt.Printf("  模型:       %s\n", p.Model)
 		}
+		if newProtocol != "" {
+			fmt.Printf("  协议:       %s\n", p.Protocol)
+		}
+		if newAuth != "" {
+			fmt.Printf("  认证:       %s\n", p.AuthLabel())
+		}
+		if cmd.Flags().Changed("models") {
+			fmt.Printf("  模型列表:   %s\n", strings.Join(p.Models, ", "))
+		}
+		if len(newRoles) > 0 {
+			fmt.Printf("  角色映射:   %s\n", formatRoles(p.EffectiveRoles()))
+		}
+		if pricingChanged {
+			if newPriceModel != "" {
+				fmt.Printf("  单价:       %s (%s)\n", formatPricing(*pricing), newPriceModel)
+			} else {
+				fmt.Printf("  单价:       %s\n", formatPricing(*pricing))
+			}
+		}
+		if len(newEnv) > 0 {
+			fmt.Printf("  环境变量:   %s\n", formatEnv(p.ExtraEnv()))
+		}
+		if len(newHeaders) > 0 {
+			fmt.Printf("  请求头:     %s\n", formatHeaderNames(p))
+		}
 		fmt.Println()
 		fmt.Printf("使用 'ccm run %s' 测试配置\n", name)
 	},
 }
 
+// editPricing 根据命令行参数更新单价，未指定的字段沿用当前或预置单价
+// 指定 --price-model 时更新该模型的单价，未指定的字段沿用该模型当前的单价
+func editPricing(cmd *cobra.Command, p provider.Provider) (*provider.Pricing, bool) {
+	flags := cmd.Flags()
+	if !flags.Changed("price-input") && !flags.Changed("price-output") && !flags.Changed("price-cache-read") &&
+		!flags.Changed("price-cache-write") && !flags.Changed("currency") {
+		return nil, false
+	}
+
+	pricing, _ := p.EffectivePricing()
+	if newPriceModel != "" {
+		pricing, _ = p.ModelPricing(newPriceModel)
+	}
+	if flags.Changed("price-input") {
+		pricing.Input = newPriceInput
+	}
+	if flags.Changed("price-output") {
+		pricing.Output = newPriceOutput
+	}
+	if flags.Changed("price-cache-read") {
+		pricing.CacheRead = newPriceCacheRead
+	}
+	if flags.Changed("price-cache-write") {
+		pricing.CacheWrite = newPriceCacheWrite
+	}
+	if flags.Changed("currency") {
+		pricing.Currency = strings.ToUpper(newCurrency)
+	}
+	return &pricing, true
+}
+
+// parseHeaders 解析 "Name: Value" 形式的请求头
+func parseHeaders(lines []string) ([]provider.EnvVar, error) {
+	var headers []provider.EnvVar
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("无效的请求头 '%s'，格式: \"Name: Value\"", line)
+		}
+		headers = append(headers, provider.EnvVar{Key: http.CanonicalHeaderKey(name), Value: strings.TrimSpace(value)})
+	}
+	return headers, nil
+}
+
+// mergeEntries 合并键值对，值为空时删除对应的键
+func mergeEntries(m map[string]string, entries []provider.EnvVar) map[string]string {
+	if m == nil {
+		m = map[string]string{}
+	}
+	for _, e := range entries {
+		if e.Value == "" {
+			delete(m, e.Key)
+		} else {
+			m[e.Key] = e.Value
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// formatEnv 显示环境变量
+func formatEnv(env []provider.EnvVar) string {
+	if len(env) == 0 {
+		return "(无)"
+	}
+	parts := make([]string, 0, len(env))
+	for _, e := range env {
+		parts = append(parts, e.Key+"="+e.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatHeaderNames 显示请求头名称（值可能包含凭据，不直接显示）
+func formatHeaderNames(p provider.Provider) string {
+	if len(p.Headers) == 0 {
+		return "(无)"
+	}
+	var names []string
+	for _, line := range strings.Split(p.CustomHeaders(), "\n") {
+		name, _, _ := strings.Cut(line, ":")
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatRoles 显示角色映射
+func formatRoles(r provider.ModelRoles) string {
+	var parts []string
+	for _, role := range provider.RoleNames {
+		if m := r.Get(role); m != "" {
+			parts = append(parts, role+"="+m)
+		}
+	}
+	if len(parts) == 0 {
+		return "(未配置，全部使用默认模型)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countNonEmpty 返回非空字符串的个数
+func countNonEmpty(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// formatPricing 显示单价
+func formatPricing(p provider.Pricing) string {
+	s := fmt.Sprintf("输入 %g / 输出 %g", p.Input, p.Output)
+	if p.CacheRead > 0 {
+		s += fmt.Sprintf(" / 缓存读取 %g", p.CacheRead)
+	}
+	if p.CacheWrite > 0 {
+		s += fmt.Sprintf(" / 缓存写入 %g", p.CacheWrite)
+	}
+	return s + fmt.Sprintf(" %s 每百万 token", p.EffectiveCurrency())
+}
+
 func init() {
-	editCmd.Flags().StringVarP(&newAPIKey, "key", "k", "", "新的 API 密钥")
+	editCmd.Flags().StringVarP(&newAPIKey, "key", "k", "", "新的 API 密钥 (可用 ${ENV} 引用环境变量)")
+	editCmd.Flags().StringVar(&newKeyCmd, "key-cmd", "", "输出 API 密钥的命令，取第一行 (如 \"pass show doubao\")")
+	editCmd.Flags().StringVar(&newKeyFile, "key-file", "", "保存 API 密钥的文件")
+	editCmd.Flags().StringVar(&newExpires, "expires", "", "API 密钥的过期时间 (2026-12-31 或 90d，为空时清除)")
+	editCmd.Flags().StringVar(&newOwner, "owner", "", "API 密钥的负责人")
 	editCmd.Flags().StringVarP(&newBaseURL, "url", "u", "", "新的 API URL")
 	editCmd.Flags().StringVarP(&newModel, "model", "m", "", "新的模型名称")
+	editCmd.Flags().StringVar(&newProtocol, "protocol", "", "新的 API 协议: anthropic 或 openai")
+	editCmd.Flags().StringVar(&newAuth, "auth", "", "新的认证方式: bearer, x-api-key, header:<名称>, query:<参数名>")
+	editCmd.Flags().StringSliceVar(&newModels, "models", nil, "可用模型列表，逗号分隔")
+	editCmd.Flags().StringArrayVar(&newRoles, "role", nil, "模型角色映射 <角色>=<模型>，角色: opus/sonnet/haiku/small_fast (可重复，模型为空时清除，包括预置的映射)")
+	editCmd.Flags().Float64Var(&newPriceInput, "price-input", 0, "输入单价 (每百万 token)")
+	editCmd.Flags().Float64Var(&newPriceOutput, "price-output", 0, "输出单价 (每百万 token)")
+	editCmd.Flags().Float64Var(&newPriceCacheRead, "price-cache-read", 0, "命中缓存的输入单价 (每百万 token)")
+	editCmd.Flags().Float64Var(&newPriceCacheWrite, "price-cache-write", 0, "写入缓存的输入单价 (每百万 token)")
+	editCmd.Flags().StringVar(&newCurrency, "currency", "", "计价货币 (如 USD、CNY)")
+	editCmd.Flags().StringVar(&newPriceModel, "price-model", "", "--price-* 和 --currency 只设置该模型的单价 (按模型名前缀匹配)")
+	editCmd.Flags().StringArrayVar(&newEnv, "env", nil, "启动时额外设置的环境变量 KEY=VALUE (可重复，值为空时删除)")
+	editCmd.Flags().StringArrayVar(&newHeaders, "header", nil, "请求供应商时附加的请求头 \"Name: Value\" (可重复，值为空时删除)")
 	rootCmd.AddCommand(editCmd)
 }
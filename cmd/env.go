@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envShell  string
+	envReveal bool
+	envProxy  string
+)
+
+// credentialVars Claude Code 读取的凭据变量
+// 启动前统一清除，只保留供应商认证方式对应的一个，避免继承的变量与之冲突
+var credentialVars = []string{"ANTHROPIC_API_KEY", "ANTHROPIC_AUTH_TOKEN"}
+
+// connectionVars providerEnv 最后设置的凭据、API 地址和请求头，不应传给会话钩子等其他程序
+var connectionVars = []string{"ANTHROPIC_API_KEY", "ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL", "ANTHROPIC_CUSTOM_HEADERS"}
+
+var envCmd = &cobra.Command{
+	Use:   "env [name]",
+	Short: "输出供应商的环境变量",
+	Long: `输出使用供应商启动 Claude Code 时设置的环境变量
+
+输出为 shell 语句，可直接 eval，使当前 shell 使用该供应商。
+默认隐藏密钥，使用 --reveal 输出真实值。不指定供应商时使用默认供应商。
+
+示例:
+  eval "$(ccm env deepseek --reveal)"                 bash / zsh
+  ccm env deepseek --reveal --shell fish | source     fish
+  ccm env deepseek --reveal --shell powershell | Invoke-Expression
+  ccm env qwen --proxy                                经本地网关连接`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		red := color.New(color.FgRed).SprintFunc()
+
+		shell := envShell
+		if shell == "" {
+			shell = detectShell()
+		}
+		if !validShell(shell) {
+			fmt.Fprintf(os.Stderr, "%s 不支持的 shell: %s (可选: sh, fish, powershell)\n", red("错误:"), shell)
+			os.Exit(1)
+		}
+
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+		t := loadLaunchProvider(name, envProxy != "")
+		name, p, apiKey := t.Name, t.Provider, t.APIKey
+
+		if p.NeedsProxy() && envProxy == "" {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 需经本地网关连接，请使用 --proxy\n", red("错误:"), name)
+			os.Exit(1)
+		}
+
+		gw := connectGateway(envProxy, name, t.Explicit)
+		env := providerEnv(name, p, apiKey, gw, defaultRunTimeout, nil)
+		if !envReveal {
+			env = maskEnv(env, apiKey, gw.Token)
+		}
+
+		fmt.Printf("# ccm env %s (%s)\n", name, p.DisplayName)
+		if !envReveal {
+			fmt.Println("# 密钥已隐藏，使用 --reveal 输出真实值")
+		}
+		for _, key := range credentialVars {
+			fmt.Println(formatUnset(shell, key))
+		}
+		for _, e := range env {
+			fmt.Println(formatExport(shell, e.Key, e.Value))
+		}
+	},
+}
+
+// providerEnv 返回使用供应商所需的环境变量（按设置顺序，后者覆盖前者）
+// 供 run、env 和 exec 共用，Claude Code 和 Anthropic SDK 都读取这些变量
+// 经本地网关连接时使用网关的访问令牌，真实密钥和附加请求头由网关注入
+func providerEnv(name string, p provider.Provider, apiKey string, gw gateway, timeout time.Duration, extra []provider.EnvVar) []provider.EnvVar {
+	env := p.ModelEnv()
+	env = append(env, provider.EnvVar{Key: "API_TIMEOUT_MS", Value: fmt.Sprint(timeout.Milliseconds())})
+	env = append(env, provider.EnvVar{Key: "CLAUDE_CONFIG_DIR", Value: config.GetClaudeConfigDir(name)})
+	env = append(env, p.ExtraEnv()...)
+	env = append(env, extra...)
+
+	// 凭据、API 地址和请求头放在最后，不会被额外的环境变量覆盖
+	if gw.URL != "" {
+		return append(env,
+			provider.EnvVar{Key: "ANTHROPIC_AUTH_TOKEN", Value: gw.Token},
+			provider.EnvVar{Key: "ANTHROPIC_BASE_URL", Value: gw.URL},
+		)
+	}
+	env = append(env,
+		p.AuthEnv(apiKey),
+		provider.EnvVar{Key: "ANTHROPIC_BASE_URL", Value: p.BaseURL},
+	)
+	if headers := p.ClientHeaders(apiKey); headers != "" {
+		env = append(env, provider.EnvVar{Key: "ANTHROPIC_CUSTOM_HEADERS", Value: headers})
+	}
+	return env
+}
+
+// applyEnv 将环境变量设置到当前进程
+func applyEnv(env []provider.EnvVar) {
+	for _, key := range credentialVars {
+		os.Unsetenv(key)
+	}
+	for _, e := range env {
+		os.Setenv(e.Key, e.Value)
+	}
+}
+
+// maskEnv 隐藏环境变量中出现的密钥
+func maskEnv(env []provider.EnvVar, secrets ...string) []provider.EnvVar {
+	masked := make([]provider.EnvVar, len(env))
+	for i, e := range env {
+		masked[i] = e
+		for _, secret := range secrets {
+			if secret != "" {
+				masked[i].Value = strings.ReplaceAll(masked[i].Value, secret, maskSecret(secret))
+			}
+		}
+	}
+	return masked
+}
+
+// maskSecret 只保留密钥首尾几位
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:3] + "****" + s[len(s)-4:]
+}
+
+// detectShell 根据运行环境推断 shell 类型
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	if filepath.Base(os.Getenv("SHELL")) == "fish" {
+		return "fish"
+	}
+	return "sh"
+}
+
+func validShell(shell string) bool {
+	switch shell {
+	case "sh", "fish", "powershell":
+		return true
+	}
+	return false
+}
+
+// formatExport 输出设置环境变量的语句
+func formatExport(shell, key, value string) string {
+	switch shell {
+	case "fish":
+		return "set -gx " + key + " " + fishQuote(value)
+	case "powershell":
+		return "$env:" + key + " = '" + strings.ReplaceAll(value, "'", "''") + "'"
+	default:
+		return "export " + key + "=" + shellQuote(value)
+	}
+}
+
+// formatUnset 输出清除环境变量的语句
+func formatUnset(shell, key string) string {
+	switch shell {
+	case "fish":
+		return "set -e " + key
+	case "powershell":
+		return "Remove-Item Env:" + key + " -ErrorAction SilentlyContinue"
+	default:
+		return "unset " + key
+	}
+}
+
+// fishQuote 将字符串转为 fish 单引号字面量
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envShell, "shell", "", "输出格式: sh, fish, powershell (默认根据 $SHELL 推断)")
+	envCmd.Flags().BoolVar(&envReveal, "reveal", false, "输出完整的密钥 (默认隐藏)")
+	envCmd.Flags().StringVar(&envProxy, "proxy", "", "经本地网关连接 (默认地址 "+proxy.DefaultAddr+")")
+	envCmd.Flags().Lookup("proxy").NoOptDefVal = proxy.DefaultAddr
+	rootCmd.AddCommand(envCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"ccm/internal/config"
+	"ccm/internal/proxy"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	execProxyAddr string
+	execEnv       []string
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [name] -- <command> [args...]",
+	Short: "在供应商的环境变量下运行任意命令",
+	Long: `在供应商的环境变量下运行任意命令
+
+设置与 'ccm run' 相同的环境变量 (ANTHROPIC_BASE_URL、凭据、模型等)，
+然后执行 -- 之后的命令，适用于 Claude Agent SDK、脚本、curl 和 CI 任务。
+不指定供应商时使用默认供应商。
+
+示例:
+  ccm exec deepseek -- python agent.py
+  ccm exec kimi -- npm test
+  ccm exec -- env                          使用默认供应商
+  ccm exec qwen --proxy -- python agent.py 经本地网关连接
+  ccm exec glm -e DEBUG=1 -- ./run.sh      额外的环境变量 (可重复)`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		n := cmd.ArgsLenAtDash()
+		if n < 0 || n == len(args) {
+			return fmt.Errorf("请在 -- 之后指定要运行的命令，如: ccm exec <name> -- <command>")
+		}
+		return cobra.MaximumNArgs(1)(cmd, args[:n])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		red := color.New(color.FgRed).SprintFunc()
+
+		n := cmd.ArgsLenAtDash()
+		name := ""
+		if n > 0 {
+			name = args[0]
+		}
+		command := args[n:]
+		t := loadLaunchProvider(name, execProxyAddr != "")
+		name, cfg, p, apiKey := t.Name, t.Config, t.Provider, t.APIKey
+
+		// 检查费用预算
+		checkBudget(cfg, name)
+
+		extraEnv, err := parseEnvOverrides(execEnv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		if p.NeedsProxy() && execProxyAddr == "" {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 需经本地网关连接，请先运行 'ccm serve' 并使用 --proxy\n", red("错误:"), name)
+			os.Exit(1)
+		}
+
+		bin, err := exec.LookPath(command[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 未找到命令 '%s'\n", red("错误:"), command[0])
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(config.GetClaudeConfigDir(name), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 创建配置目录失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		applyEnv(providerEnv(name, p, apiKey, connectGateway(execProxyAddr, name, t.Explicit), defaultRunTimeout, extraEnv))
+
+		// 使用 syscall.Exec 替换当前进程，退出码由命令决定
+		if err := syscall.Exec(bin, command, os.Environ()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 执行 %s 失败: %v\n", red("错误:"), command[0], err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	execCmd.Flags().StringVar(&execProxyAddr, "proxy", "", "经本地网关连接 (默认地址 "+proxy.DefaultAddr+")")
+	execCmd.Flags().Lookup("proxy").NoOptDefVal = proxy.DefaultAddr
+	execCmd.Flags().StringArrayVarP(&execEnv, "env", "e", nil, "额外的环境变量 KEY=VALUE (可重复)")
+	rootCmd.AddCommand(execCmd)
+}
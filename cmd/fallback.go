@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ccm/internal/config"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var clearFallback bool
+
+var fallbackCmd = &cobra.Command{
+	Use:   "fallback [name...]",
+	Short: "设置或显示故障转移顺序",
+	Long: `设置或显示故障转移顺序
+
+当前供应商返回 429/5xx、超时或网络错误时，本地网关 (ccm serve)
+会按此顺序依次重试其他供应商。需要通过 'ccm run --proxy' 启动 Claude Code。
+
+示例:
+  ccm fallback                         显示当前故障转移顺序
+  ccm fallback doubao deepseek wanjie  设置故障转移顺序
+  ccm fallback --clear                 清除故障转移`,
+	Run: func(cmd *cobra.Command, args []string) {
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		cyan := color.New(color.FgCyan).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		if clearFallback {
+			if err := config.SetFallback(nil); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已清除故障转移顺序\n", green("✓"))
+			return
+		}
+
+		// 无参数时显示当前顺序
+		if len(args) == 0 {
+			if len(cfg.Fallback) == 0 {
+				fmt.Println("尚未设置故障转移顺序")
+				fmt.Println()
+				fmt.Printf("使用 %s 设置\n", cyan("ccm fallback <name> <name>..."))
+				return
+			}
+			fmt.Printf("故障转移顺序: %s\n", cyan(strings.Join(cfg.Fallback, " -> ")))
+			return
+		}
+
+		// 验证供应商是否已配置
+		for _, name := range args {
+			if _, ok := cfg.Providers[name]; !ok {
+				fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
+				fmt.Fprintf(os.Stderr, "请先运行: ccm add %s --key \"你的API密钥\"\n", name)
+				os.Exit(1)
+			}
+		}
+
+		if err := config.SetFallback(args); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s 已设置故障转移顺序: %s\n", green("✓"), cyan(strings.Join(args, " -> ")))
+		fmt.Println()
+		fmt.Printf("使用 %s 启动网关，%s 启动 Claude Code\n", cyan("ccm serve"), cyan("ccm run --proxy"))
+	},
+}
+
+func init() {
+	fallbackCmd.Flags().BoolVar(&clearFallback, "clear", false, "清除故障转移顺序")
+	rootCmd.AddCommand(fallbackCmd)
+}
@@ -4,19 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"ccm/internal/config"
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
-const scriptTemplate = `#!/usr/bin/env bash
-# Claude Code - {{.DisplayName}}
-# 由 ccm generate 自动生成
-
-# 查找 claude 可执行文件
+// claudeLookup 查找 claude 可执行文件的脚本片段
+const claudeLookup = `# 查找 claude 可执行文件
 if [ -x "$HOME/claude-model/node_modules/.bin/claude" ]; then
     CLAUDE_BIN="$HOME/claude-model/node_modules/.bin/claude"
 elif command -v claude &> /dev/null; then
@@ -26,13 +26,33 @@ else
     echo "请先安装: npm install -g @anthropic-ai/claude-code"
     exit 1
 fi
+`
 
+const scriptTemplate = `#!/usr/bin/env bash
+# Claude Code - {{.DisplayName}}
+# 由 ccm generate 自动生成
+
+` + claudeLookup + `
 # 设置环境变量
-export ANTHROPIC_AUTH_TOKEN="{{.APIKey}}"
-export ANTHROPIC_BASE_URL="{{.BaseURL}}"
-export ANTHROPIC_MODEL="{{.Model}}"
+unset ANTHROPIC_API_KEY ANTHROPIC_AUTH_TOKEN
+{{- range .ModelEnv}}
+export {{.Key}}={{shellQuote .Value}}
+{{- end}}
 export API_TIMEOUT_MS=300000
-export CLAUDE_CONFIG_DIR="$HOME/claude-model/configs/.claude-{{.Name}}"
+{{- range .ExtraEnv}}
+export {{.Key}}={{shellQuote .Value}}
+{{- end}}
+export CLAUDE_CONFIG_DIR="$HOME/claude-model/configs/.claude-"{{shellQuote .Name}}
+# 凭据和 API 地址最后设置，不会被额外的环境变量覆盖
+{{- if .TokenFile}}
+export {{.AuthVar}}="$(cat {{shellQuote .TokenFile}})"
+{{- else}}
+export {{.AuthVar}}={{shellQuote .APIKey}}
+{{- end}}
+export ANTHROPIC_BASE_URL={{shellQuote .BaseURL}}
+{{- if .CustomHeaders}}
+export ANTHROPIC_CUSTOM_HEADERS={{shellQuote .CustomHeaders}}
+{{- end}}
 
 # 确保配置目录存在
 mkdir -p "$CLAUDE_CONFIG_DIR"
@@ -41,13 +61,81 @@ mkdir -p "$CLAUDE_CONFIG_DIR"
 exec "$CLAUDE_BIN" "$@"
 `
 
+// runtimeScriptTemplate 启动时通过 'ccm env' 获取密钥的脚本，脚本中不包含密钥
+const runtimeScriptTemplate = `#!/usr/bin/env bash
+# Claude Code - {{.DisplayName}}
+# 由 ccm generate 自动生成，启动时通过 ccm 获取密钥，脚本中不包含密钥
+
+` + claudeLookup + `
+# 查找 ccm
+if command -v ccm &> /dev/null; then
+    CCM_BIN="$(command -v ccm)"
+elif [ -x {{shellQuote .CCMBin}} ]; then
+    CCM_BIN={{shellQuote .CCMBin}}
+else
+    echo "错误: 未找到 ccm 命令"
+    exit 1
+fi
+
+# 设置环境变量 (密钥来自配置、密钥库或密钥来源命令)
+CCM_ENV="$("$CCM_BIN" env {{shellQuote .Name}} --reveal --shell sh{{if .NeedsProxy}} --proxy{{end}})" || exit 1
+eval "$CCM_ENV"
+unset CCM_ENV
+
+# 确保配置目录存在
+mkdir -p "$CLAUDE_CONFIG_DIR"
+
+# 启动 claude
+exec "$CLAUDE_BIN" "$@"
+`
+
+// scriptData 脚本模板数据
+// 需要网关的供应商 (OpenAI 协议、query 认证) 改为连接本地网关，由网关注入密钥、附加请求头并转换协议
+type scriptData struct {
+	provider.Provider
+	AuthVar       string
+	APIKey        string
+	TokenFile     string // 经网关连接时，脚本启动时从该文件读取网关的访问令牌
+	BaseURL       string
+	CustomHeaders string
+	CCMBin        string // 运行时模式下 PATH 中没有 ccm 时使用的路径
+}
+
+func newScriptData(p provider.Provider) scriptData {
+	if p.NeedsProxy() {
+		// 网关每次启动生成新的访问令牌，脚本启动时读取
+		return scriptData{Provider: p, AuthVar: "ANTHROPIC_AUTH_TOKEN", TokenFile: proxy.TokenFile(proxy.DefaultAddr), BaseURL: "http://" + proxy.DefaultAddr + "/providers/" + p.Name}
+	}
+	return scriptData{
+		Provider:      p,
+		AuthVar:       p.AuthEnv(p.APIKey).Key,
+		APIKey:        p.APIKey,
+		BaseURL:       p.BaseURL,
+		CustomHeaders: p.ClientHeaders(p.APIKey),
+	}
+}
+
+// shellQuote 将字符串转为 shell 单引号字面量
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var generateEmbedKey bool
+
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "为已配置的供应商生成启动脚本",
 	Long: `为所有已配置的供应商生成 shell 启动脚本
 
 生成的脚本位于 ~/claude-model/bin/ 目录
-将该目录加入 PATH 后，可直接使用 claude-<供应商名> 命令`,
+将该目录加入 PATH 后，可直接使用 claude-<供应商名> 命令
+
+脚本默认在启动时通过 'ccm env' 获取密钥，脚本中不包含密钥，可以放心同步到
+dotfiles 仓库。使用 --embed-key 时，明文保存在配置中的密钥直接写入脚本，脚本不再
+依赖 ccm，仅所有者可读 (0700)。密钥保存在密钥库中或来自命令、文件、环境变量的
+供应商总是在启动时获取密钥。
+
+OpenAI 协议和查询参数认证的供应商会通过本地网关连接，使用前需先运行 'ccm serve'`,
 	Run: func(cmd *cobra.Command, args []string) {
 		green := color.New(color.FgGreen).SprintFunc()
 		red := color.New(color.FgRed).SprintFunc()
@@ -76,11 +164,16 @@ var generateCmd = &cobra.Command{
 		}
 
 		// 解析模板
-		tmpl, err := template.New("script").Parse(scriptTemplate)
+		funcs := template.FuncMap{"shellQuote": shellQuote}
+		tmpl, err := template.New("script").Funcs(funcs).Parse(scriptTemplate)
+		if err == nil {
+			_, err = tmpl.New("runtime").Parse(runtimeScriptTemplate)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s 解析模板失败: %v\n", red("错误:"), err)
 			os.Exit(1)
 		}
+		ccmBin, _ := os.Executable()
 
 		fmt.Println()
 		fmt.Println(cyan("生成启动脚本:"))
@@ -88,25 +181,44 @@ var generateCmd = &cobra.Command{
 
 		count := 0
 		for name, p := range cfg.Providers {
-			if p.APIKey == "" {
+			if !p.HasAPIKey() {
 				continue
 			}
 
+			// 密钥未以明文保存在配置中时，不应复制到脚本
+			atRuntime := !generateEmbedKey || !config.PlaintextKey(p)
+			data := newScriptData(p)
+			data.CCMBin = ccmBin
+			tmplName, mode := "script", os.FileMode(0700)
+			if atRuntime {
+				tmplName, mode = "runtime", 0755
+			}
+
 			scriptPath := filepath.Join(binDir, "claude-"+name)
-			f, err := os.OpenFile(scriptPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+			f, err := os.OpenFile(scriptPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 			if err != nil {
 				fmt.Printf("  %s %s: %v\n", red("✗"), name, err)
 				continue
 			}
+			// 已存在的文件保留原权限，需要显式修改
+			if err := f.Chmod(mode); err != nil {
+				f.Close()
+				fmt.Printf("  %s %s: %v\n", red("✗"), name, err)
+				continue
+			}
 
-			if err := tmpl.Execute(f, p); err != nil {
+			if err := tmpl.ExecuteTemplate(f, tmplName, data); err != nil {
 				f.Close()
 				fmt.Printf("  %s %s: %v\n", red("✗"), name, err)
 				continue
 			}
 			f.Close()
 
-			fmt.Printf("  %s claude-%s\n", green("✓"), name)
+			note := ""
+			if !atRuntime {
+				note = gray(" (脚本中包含密钥)")
+			}
+			fmt.Printf("  %s claude-%s%s\n", green("✓"), name, note)
 			count++
 		}
 
@@ -124,5 +236,9 @@ var generateCmd = &cobra.Command{
 }
 
 func init() {
+	generateCmd.Flags().BoolVar(&generateEmbedKey, "embed-key", false, "将明文保存的密钥写入脚本，脚本不再依赖 ccm")
+	// 运行时获取密钥已是默认行为，保留 --runtime 以兼容已有的用法
+	generateCmd.Flags().Bool("runtime", true, "")
+	_ = generateCmd.Flags().MarkDeprecated("runtime", "脚本默认已在启动时获取密钥")
 	rootCmd.AddCommand(generateCmd)
 }
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/history"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyDir   string
+	historyHere  bool
+	historySince string
+	historyLimit int
+	historyJSON  bool
+	historyRerun int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [name]",
+	Short: "查看 Claude Code 启动历史",
+	Long: `查看 Claude Code 启动历史
+
+记录通过 'ccm run'、'ccm switch' 和 TUI 启动的每次会话: 供应商、模型、工作目录、
+参数和时间。监管模式 (--supervise) 下还会记录退出码。
+
+示例:
+  ccm history                    最近 20 次启动
+  ccm history deepseek           只看 DeepSeek
+  ccm history --here --since 2d  当前目录最近 48 小时的启动
+  ccm history --rerun 1          在原目录以相同的供应商、模型和参数重新启动最近一次
+
+重新启动时还会恢复当时的 --small-model 和 --timeout 覆盖项。--env 只记录变量名，
+值可能是密钥，不会保存，重新启动时需要在终端中再次输入。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		red := color.New(color.FgRed).SprintFunc()
+
+		since, err := parseSince(historySince)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		dir := historyDir
+		if historyHere {
+			dir = "."
+		}
+		if dir != "" {
+			if dir, err = filepath.Abs(dir); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+		}
+
+		all, err := history.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 读取启动历史失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		// 过滤，最近的排在前面
+		var entries []history.Entry
+		for i := len(all) - 1; i >= 0; i-- {
+			e := all[i]
+			if len(args) > 0 && e.Provider != args[0] {
+				continue
+			}
+			if dir != "" && e.Dir != dir {
+				continue
+			}
+			if e.Time.Before(since) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+
+		if historyRerun > 0 {
+			if historyRerun > len(entries) {
+				fmt.Fprintf(os.Stderr, "%s 没有第 %d 条启动记录\n", red("错误:"), historyRerun)
+				os.Exit(1)
+			}
+			rerun(entries[historyRerun-1])
+			return
+		}
+
+		if historyLimit > 0 && len(entries) > historyLimit {
+			entries = entries[:historyLimit]
+		}
+
+		if historyJSON {
+			if entries == nil {
+				entries = []history.Entry{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(entries)
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("没有启动记录")
+			return
+		}
+		printHistory(entries)
+	},
+}
+
+// printHistory 以表格显示启动记录
+func printHistory(entries []history.Entry) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Println()
+	fmt.Printf("  %s %s %s %s %s %s\n", padRight("#", 4), padRight("时间", 17), padRight("供应商", 12), padRight("模型", 24), padRight("退出", 5), "目录")
+	for i, e := range entries {
+		exit := gray(padRight("-", 5))
+		if e.Finished() {
+			code := padRight(fmt.Sprint(*e.ExitCode), 5)
+			if *e.ExitCode == 0 {
+				exit = green(code)
+			} else {
+				exit = red(code)
+			}
+		}
+		line := fmt.Sprintf("  %s %s %s %s %s %s",
+			padRight(fmt.Sprint(i+1), 4),
+			padRight(e.Time.Local().Format("2006-01-02 15:04"), 17),
+			cyan(padRight(e.Provider, 12)),
+			padRight(e.Model, 24),
+			exit,
+			shortenHome(e.Dir),
+		)
+		if len(e.EnvKeys) > 0 {
+			line += gray("  -e " + strings.Join(e.EnvKeys, " -e "))
+		}
+		if len(e.Args) > 0 {
+			line += gray("  -- " + strings.Join(e.Args, " "))
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+	fmt.Println(gray("使用 'ccm history --rerun <#>' 重新启动"))
+}
+
+// rerun 在原工作目录以相同的供应商、模型、参数和本次启动的覆盖项重新启动
+func rerun(e history.Entry) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	if e.Dir != "" {
+		if err := os.Chdir(e.Dir); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 无法进入目录 %s，在当前目录启动: %v\n", yellow("警告:"), e.Dir, err)
+		}
+	}
+	// 只有与当前默认模型不同时才覆盖
+	if cfg, err := config.Load(); err == nil && cfg.Providers[e.Provider].Model != e.Model {
+		runModel = e.Model
+	}
+	runProxyAddr = e.Proxy
+	runSmallModel = e.SmallModel
+	runEnv = readEnvValues(e.EnvKeys)
+	if e.Timeout != "" {
+		timeout, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 启动记录中的超时时间无效: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		runTimeout = timeout
+	}
+
+	fmt.Printf("重新启动: %s (%s) %s\n\n", cyan(e.Provider), e.Model, shortenHome(e.Dir))
+	runProvider(history.SourceRerun, e.Provider, e.Args)
+}
+
+// readEnvValues 重新输入启动记录中 --env 变量的值（不回显），启动历史不保存这些值
+// 非交互环境无法输入时退出
+func readEnvValues(keys []string) []string {
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	if len(keys) == 0 {
+		return nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "%s 该记录通过 --env 设置了 %s，启动历史不保存其值，需要在终端中重新输入\n", red("错误:"), strings.Join(keys, ", "))
+		fmt.Fprintf(os.Stderr, "也可以直接运行: %s\n", cyan("ccm run <name> -e KEY=VALUE"))
+		os.Exit(1)
+	}
+
+	fmt.Println("该记录通过 --env 设置了以下环境变量，请重新输入它们的值:")
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fmt.Fprintf(os.Stderr, "  %s=", key)
+		value, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 读取 %s 失败: %v\n", red("错误:"), key, err)
+			os.Exit(1)
+		}
+		pairs = append(pairs, key+"="+string(value))
+	}
+	fmt.Println()
+	return pairs
+}
+
+// shortenHome 将主目录显示为 ~
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return filepath.Join("~", rest)
+	}
+	return path
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyDir, "dir", "", "只显示在该目录启动的记录")
+	historyCmd.Flags().BoolVar(&historyHere, "here", false, "只显示在当前目录启动的记录")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "起始时间: 7d、24h 或 2025-01-01")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "最多显示的记录数 (0 表示不限)")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "以 JSON 格式输出")
+	historyCmd.Flags().IntVar(&historyRerun, "rerun", 0, "重新启动第 N 条记录 (按当前过滤条件编号，1 为最近一次)")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -109,7 +109,11 @@ var initCmd = &cobra.Command{
 		}
 
 		// 保存配置
+		if cfg != nil {
+			ensureUnlocked(cfg)
+		}
 		p.APIKey = apiKey
+		p.KeyMeta = provider.KeyMeta{CreatedAt: keyCreatedAt()}
 		if err := config.AddProvider(p); err != nil {
 			fmt.Printf("保存配置失败: %v\n", err)
 			return
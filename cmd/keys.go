@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keysAdd      string
+	keysRemove   string
+	keysKey      string
+	keysKeyCmd   string
+	keysKeyFile  string
+	keysStrategy string
+	keysExpires  string
+	keysOwner    string
+	keysReset    bool
+)
+
+// keyLabelPattern 密钥标签允许的字符
+var keyLabelPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys <name>",
+	Short: "管理供应商的多个 API Key",
+	Long: `管理供应商的多个带标签的 API Key
+
+团队共用多个厂商账号分摊限流时，可为一个供应商配置多个密钥，
+启动和经本地网关转发时按策略选择其中一个:
+  round-robin  依次轮换 (默认)
+  random       随机选择
+  least-429    优先选择最久未被限流 (HTTP 429) 的密钥
+
+本地网关和 'ccm test' 会自动标记失效的密钥，之后选择时跳过:
+  - 返回 401 或提示密钥无效的 403 的密钥标记为已吊销，需使用 --reset 恢复
+  - 其他 403 (如模型或地区无权限) 只换用其他密钥，不标记
+  - 返回 402 或提示余额不足的密钥标记为已耗尽，1 小时后重新尝试
+网关遇到失效或被限流的密钥时，会先换用同一供应商的下一个密钥重试。
+
+示例:
+  ccm keys openai                                   查看密钥和状态
+  ccm keys openai --add team-a --key "sk-..."       添加密钥 (同名时替换)
+  ccm keys openai --add team-b --key-cmd "pass show openai/team-b"
+  ccm keys openai --add team-c --key-file ~/.secrets/openai-c
+  ccm keys openai --add team-a --expires 2026-12-31 --owner alice
+                                                    记录已有密钥的过期时间和负责人
+  ccm keys openai --remove team-a                   删除密钥
+  ccm keys openai --strategy least-429              设置选择策略
+  ccm keys openai --reset                           清除所有密钥的吊销、耗尽标记
+
+添加第一个密钥时，原有的 API Key 会保存为 'default'。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		p, ok := cfg.Providers[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 不存在\n", red("错误:"), name)
+			fmt.Fprintf(os.Stderr, "可用供应商: ccm list\n")
+			os.Exit(1)
+		}
+
+		if countNonEmpty(keysKey, keysKeyCmd, keysKeyFile, keysExpires, keysOwner) > 0 && keysAdd == "" {
+			fmt.Fprintf(os.Stderr, "%s --key、--key-cmd、--key-file、--expires 和 --owner 需要与 --add <标签> 一起使用\n", red("错误:"))
+			os.Exit(1)
+		}
+
+		updated := false
+		if keysAdd != "" {
+			p = addKey(cfg, name, p)
+			updated = true
+		}
+		if keysRemove != "" {
+			i := p.FindKey(keysRemove)
+			if i < 0 {
+				fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 没有标签为 '%s' 的密钥\n", red("错误:"), name, keysRemove)
+				os.Exit(1)
+			}
+			p.Keys = append(p.Keys[:i], p.Keys[i+1:]...)
+			_ = config.ResetKeyState(name, keysRemove)
+			fmt.Printf("%s 已删除密钥: %s\n", green("✓"), keysRemove)
+			updated = true
+		}
+		if keysStrategy != "" {
+			if !validKeyStrategy(keysStrategy) {
+				fmt.Fprintf(os.Stderr, "%s 不支持的策略: %s (可选: %s)\n", red("错误:"), keysStrategy, formatKeyStrategies())
+				os.Exit(1)
+			}
+			p.KeyStrategy = provider.KeyStrategy(keysStrategy)
+			fmt.Printf("%s 密钥选择策略: %s\n", green("✓"), keysStrategy)
+			updated = true
+		}
+
+		if updated {
+			cfg.Providers[name] = p
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+		}
+		if keysReset {
+			if err := config.ResetKeyState(name, ""); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 清除密钥状态失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已清除所有密钥的吊销、耗尽标记\n", green("✓"))
+		}
+
+		if len(p.Keys) == 0 {
+			fmt.Printf("供应商 '%s' 未配置多个密钥\n", name)
+			fmt.Println(gray(fmt.Sprintf("使用 'ccm keys %s --add <标签> --key \"...\"' 添加", name)))
+			return
+		}
+		printKeys(cfg, name, p)
+	},
+}
+
+// addKey 添加或替换带标签的密钥，首次添加时将原有的 API Key 保存为 default
+func addKey(cfg *config.Config, name string, p provider.Provider) provider.Provider {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	if !keyLabelPattern.MatchString(keysAdd) {
+		fmt.Fprintf(os.Stderr, "%s 无效的标签 '%s'，只能包含字母、数字、'.'、'_' 和 '-'\n", red("错误:"), keysAdd)
+		os.Exit(1)
+	}
+	expiresAt, err := parseKeyExpiry(keysExpires)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	n := countNonEmpty(keysKey, keysKeyCmd, keysKeyFile)
+	if i := p.FindKey(keysAdd); n == 0 && i >= 0 && countNonEmpty(keysExpires, keysOwner) > 0 {
+		// 只更新已有密钥的过期时间和负责人
+		if !expiresAt.IsZero() {
+			p.Keys[i].ExpiresAt = expiresAt
+		}
+		if keysOwner != "" {
+			p.Keys[i].Owner = keysOwner
+		}
+		fmt.Printf("%s 已更新密钥信息: %s (%s)\n", green("✓"), keysAdd, formatKeyMeta(p.Keys[i].KeyMeta))
+		return p
+	}
+	if n == 0 {
+		fmt.Fprintf(os.Stderr, "%s 请使用 --key、--key-cmd 或 --key-file 指定密钥\n", red("错误:"))
+		os.Exit(1)
+	} else if n > 1 {
+		fmt.Fprintf(os.Stderr, "%s --key、--key-cmd 和 --key-file 只能指定一个\n", red("错误:"))
+		os.Exit(1)
+	}
+	if keysKey != "" || config.IsEncrypted(p.APIKey) {
+		// 启用密钥库时需要解锁才能加密保存
+		ensureUnlocked(cfg)
+	}
+
+	if len(p.Keys) == 0 && (p.APIKey != "" || p.APIKeyCmd != "" || p.APIKeyFile != "") && keysAdd != "default" {
+		apiKey, err := config.RevealAPIKey(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		p.Keys = []provider.APIKeyEntry{{Label: "default", Key: apiKey, KeyCmd: p.APIKeyCmd, KeyFile: p.APIKeyFile, KeyMeta: p.KeyMeta}}
+		fmt.Printf("%s 原有的 API Key 已保存为 'default'\n", green("✓"))
+	}
+	// 密钥只保存在 keys 中
+	p.APIKey, p.APIKeyCmd, p.APIKeyFile = "", "", ""
+	p.KeyMeta = provider.KeyMeta{}
+
+	entry := provider.APIKeyEntry{Label: keysAdd, Key: keysKey, KeyCmd: keysKeyCmd, KeyFile: keysKeyFile}
+	entry.KeyMeta = provider.KeyMeta{CreatedAt: keyCreatedAt(), ExpiresAt: expiresAt, Owner: keysOwner}
+	if i := p.FindKey(keysAdd); i >= 0 {
+		if entry.Owner == "" {
+			entry.Owner = p.Keys[i].Owner
+		}
+		p.Keys[i] = entry
+		fmt.Printf("%s 已替换密钥: %s\n", green("✓"), keysAdd)
+	} else {
+		p.Keys = append(p.Keys, entry)
+		fmt.Printf("%s 已添加密钥: %s\n", green("✓"), keysAdd)
+	}
+	// 新密钥不沿用旧密钥的吊销、耗尽标记
+	_ = config.ResetKeyState(name, keysAdd)
+	return p
+}
+
+// printKeys 以表格显示供应商的密钥和状态
+func printKeys(cfg *config.Config, name string, p provider.Provider) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	states := config.KeyStates(name)
+	now := time.Now()
+
+	fmt.Println()
+	fmt.Printf("%s %s\n", cyan(fmt.Sprintf("供应商 %s 的密钥", name)), gray("(策略: "+string(p.EffectiveKeyStrategy())+")"))
+	fmt.Println()
+	fmt.Printf("  %s %s %s %s %s\n", padRight("标签", 12), padRight("状态", 10), padRight("最近使用", 12), padRight("最近限流", 12), "来源")
+	for _, k := range p.Keys {
+		s := states[k.Label]
+		status := green(padRight("可用", 10))
+		switch {
+		case s.Status == config.KeyRevoked:
+			status = red(padRight("已吊销", 10))
+		case s.Status == config.KeyExhausted && !s.Available(now):
+			status = yellow(padRight("已耗尽", 10))
+		}
+		line := fmt.Sprintf("  %s %s %s %s %s",
+			cyan(padRight(k.Label, 12)),
+			status,
+			padRight(formatKeyTime(s.LastUsed), 12),
+			padRight(formatKeyTime(s.Last429), 12),
+			config.DescribeKeyEntry(cfg, name, k),
+		)
+		if s.Status != config.KeyActive && s.Reason != "" {
+			line += gray(fmt.Sprintf("  (%s, %s)", s.Reason, s.MarkedAt.Local().Format("01-02 15:04")))
+		}
+		if !k.KeyMeta.IsZero() {
+			line += gray("  " + formatKeyMeta(k.KeyMeta))
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// formatKeyTime 显示密钥的使用时间，未记录时显示 -
+func formatKeyTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("01-02 15:04")
+}
+
+// validKeyStrategy 检查密钥选择策略是否有效
+func validKeyStrategy(s string) bool {
+	for _, k := range provider.KeyStrategies {
+		if string(k) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// formatKeyStrategies 列出支持的密钥选择策略
+func formatKeyStrategies() string {
+	names := make([]string, len(provider.KeyStrategies))
+	for i, k := range provider.KeyStrategies {
+		names[i] = string(k)
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	keysCmd.Flags().StringVar(&keysAdd, "add", "", "添加或替换指定标签的密钥")
+	keysCmd.Flags().StringVar(&keysRemove, "remove", "", "删除指定标签的密钥")
+	keysCmd.Flags().StringVarP(&keysKey, "key", "k", "", "API 密钥 (可用 ${ENV} 引用环境变量)")
+	keysCmd.Flags().StringVar(&keysKeyCmd, "key-cmd", "", "输出 API 密钥的命令，取第一行")
+	keysCmd.Flags().StringVar(&keysKeyFile, "key-file", "", "保存 API 密钥的文件")
+	keysCmd.Flags().StringVar(&keysStrategy, "strategy", "", "密钥选择策略 (round-robin/random/least-429)")
+	keysCmd.Flags().StringVar(&keysExpires, "expires", "", "密钥的过期时间 (2026-12-31 或 90d)")
+	keysCmd.Flags().StringVar(&keysOwner, "owner", "", "密钥的负责人")
+	keysCmd.Flags().BoolVar(&keysReset, "reset", false, "清除所有密钥的吊销、耗尽标记")
+	rootCmd.AddCommand(keysCmd)
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"ccm/internal/config"
+	"ccm/internal/history"
 	"ccm/internal/provider"
 	"ccm/internal/ui"
 
@@ -44,7 +46,7 @@ var listCmd = &cobra.Command{
 		for _, name := range provider.PresetOrder {
 			preset := provider.Presets[name]
 			p, hasProvider := cfg.Providers[name]
-			configured := hasProvider && p.APIKey != ""
+			configured := hasProvider && p.HasAPIKey()
 
 			status := red("✗")
 			statusText := gray("未配置")
@@ -76,6 +78,16 @@ var listCmd = &cobra.Command{
 			fmt.Println()
 		}
 
+		// 即将过期或超过轮换期限的密钥
+		if warnings := cfg.AllKeyWarnings(time.Now()); len(warnings) > 0 {
+			fmt.Println(yellow("密钥提醒:"))
+			for _, w := range warnings {
+				fmt.Printf("  %s %s\n", yellow("⚠"), describeKeyWarning(w))
+			}
+			fmt.Printf("  %s\n", gray("ccm rotate <name> --key \"...\"  # 更换密钥，旧密钥短期保留为备用"))
+			fmt.Println()
+		}
+
 		// 交互模式
 		if interactiveMode {
 			runInteractiveMode(cfg)
@@ -149,7 +161,7 @@ func runInteractiveMode(cfg *config.Config) {
 	switch idx {
 	case 0: // run
 		fmt.Printf("\n正在启动 Claude Code (%s)...\n", selectedName)
-		runCmd.Run(nil, []string{selectedName})
+		runProvider(history.SourceList, selectedName, nil)
 	case 1: // add
 		fmt.Println()
 		p := provider.Presets[selectedName]
@@ -160,7 +172,11 @@ func runInteractiveMode(cfg *config.Config) {
 		if err != nil {
 			return
 		}
+		if cfg != nil {
+			ensureUnlocked(cfg)
+		}
 		p.APIKey = apiKey
+		p.KeyMeta = provider.KeyMeta{CreatedAt: keyCreatedAt()}
 		if err := config.AddProvider(p); err != nil {
 			fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
 			return
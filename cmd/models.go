@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"ccm/internal/config"
+	"ccm/internal/probe"
+	"ccm/internal/provider"
+	"ccm/internal/ui"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelsPick bool
+	modelsSave bool
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models <name>",
+	Short: "查询供应商的可用模型",
+	Long: `查询供应商的可用模型
+
+调用供应商的模型列表接口 (Anthropic 协议为 /v1/models，OpenAI 协议为 /models)，
+列出可用的模型 ID。查询结果会被缓存，供 TUI 编辑对话框自动补全使用。
+
+示例:
+  ccm models doubao          列出豆包的可用模型
+  ccm models doubao -i       交互选择模型并设为默认模型
+  ccm models kimi --save     将模型列表保存到配置 (可用模型)`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		p, ok := cfg.Providers[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
+			os.Exit(1)
+		}
+
+		ensureUnlocked(cfg)
+		apiKey, _, err := config.ResolveAPIKey(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		models, err := probe.ListModels(context.Background(), p, apiKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 查询模型列表失败: %v\n", red("错误:"), err)
+			fmt.Fprintln(os.Stderr, "部分供应商不提供模型列表接口，可使用 'ccm edit <name> --models a,b' 手动配置")
+			os.Exit(1)
+		}
+		if len(models) == 0 {
+			fmt.Println("供应商没有返回任何模型")
+			return
+		}
+		if err := probe.SaveModelCache(name, models); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 缓存模型列表失败: %v\n", yellow("警告:"), err)
+		}
+
+		if modelsSave {
+			p.Models = models
+		}
+
+		if modelsPick {
+			selected, err := ui.SelectItem(models, p.Model, fmt.Sprintf("选择 %s 的默认模型 (输入可过滤)", name))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "取消选择")
+				return
+			}
+			p.Model = selected
+		}
+
+		if modelsPick || modelsSave {
+			cfg.Providers[name] = p
+			if err := config.Save(cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			if modelsSave {
+				fmt.Printf("%s 已保存 %d 个可用模型\n", green("✓"), len(models))
+			}
+			if modelsPick {
+				fmt.Printf("%s 默认模型: %s\n", green("✓"), yellow(p.Model))
+			}
+			return
+		}
+
+		roles := p.EffectiveRoles()
+		fmt.Println()
+		for _, m := range models {
+			line := "  " + m
+			if m == p.Model {
+				line = "  " + yellow(m) + yellow(" ★")
+			}
+			if tags := roleTags(roles, m); tags != "" {
+				line += " " + gray("["+tags+"]")
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+		fmt.Printf("共 %d 个模型", len(models))
+		if !containsString(models, p.Model) {
+			fmt.Printf("，%s 当前默认模型 %s 不在列表中", yellow("注意:"), p.Model)
+		}
+		fmt.Println()
+	},
+}
+
+// roleTags 返回使用该模型的角色名称
+func roleTags(roles provider.ModelRoles, model string) string {
+	var tags []string
+	for _, role := range provider.RoleNames {
+		if roles.Get(role) == model {
+			tags = append(tags, role)
+		}
+	}
+	return strings.Join(tags, ",")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	modelsCmd.Flags().BoolVarP(&modelsPick, "interactive", "i", false, "交互选择模型并设为默认模型")
+	modelsCmd.Flags().BoolVar(&modelsSave, "save", false, "将模型列表保存为供应商的可用模型")
+	rootCmd.AddCommand(modelsCmd)
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"ccm/internal/config"
 	"ccm/internal/ui"
@@ -61,8 +60,7 @@ var removeCmd = &cobra.Command{
 		}
 
 		// 删除对应的配置目录
-		home, _ := os.UserHomeDir()
-		configDir := filepath.Join(home, "claude-model", "configs", ".claude-"+name)
+		configDir := config.GetClaudeConfigDir(name)
 		if _, err := os.Stat(configDir); err == nil {
 			os.RemoveAll(configDir)
 		}
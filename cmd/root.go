@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"ccm/internal/config"
+	"ccm/internal/history"
 	"ccm/internal/ui"
 
 	"github.com/spf13/cobra"
@@ -29,6 +31,11 @@ var rootCmd = &cobra.Command{
   /           搜索
   q           退出`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Unlock the vault up front; the TUI cannot prompt for a passphrase
+		if cfg, err := config.Load(); err == nil {
+			ensureUnlocked(cfg)
+		}
+
 		// Launch TUI when no subcommand is provided
 		result, err := ui.RunTUI()
 		if err != nil {
@@ -38,7 +45,7 @@ var rootCmd = &cobra.Command{
 
 		// If user selected a provider to run, execute it
 		if result != nil && result.RunProvider != "" {
-			runCmd.Run(cmd, []string{result.RunProvider})
+			runProvider(history.SourceTUI, result.RunProvider, nil)
 		}
 	},
 }
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/probe"
+	"ccm/internal/provider"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rotateLabel    string
+	rotateKey      string
+	rotateKeyCmd   string
+	rotateKeyFile  string
+	rotateGrace    time.Duration
+	rotateExpires  string
+	rotateOwner    string
+	rotateRollback bool
+	rotateNoTest   bool
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "更换供应商的 API Key",
+	Long: `更换供应商的 API Key，旧密钥短期保留为备用
+
+新密钥会先发送一个测试请求验证，被拒绝 (HTTP 401/403) 时不会更换。
+更换后旧密钥在 --grace 时间内保留在 retired_keys 中，本地网关在新密钥
+失效或被限流时自动换用旧密钥，到期后不再使用。
+
+配置了多个密钥时，使用 --label 指定要更换的密钥。
+
+示例:
+  ccm rotate openai --key "sk-new..."                 更换密钥，旧密钥保留 24 小时
+  ccm rotate openai --key-cmd "pass show openai" --grace 72h
+  ccm rotate openai --label team-a --key "sk-..."     更换多个密钥中的一个
+  ccm rotate openai --key "sk-..." --expires 90d      记录新密钥的过期时间
+  ccm rotate openai --rollback                        回退到保留的旧密钥
+
+过期时间可使用 2026-12-31、RFC3339 时间或 90d (90 天后)。
+轮换提醒的天数可在 providers.yaml 中配置:
+  rotation:
+    max_age_days: 90   密钥最长使用天数，负数表示不检查
+    warn_days: 14      过期前提前提醒的天数`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		green := color.New(color.FgGreen).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		p, ok := cfg.Providers[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 不存在\n", red("错误:"), name)
+			fmt.Fprintf(os.Stderr, "可用供应商: ccm list\n")
+			os.Exit(1)
+		}
+		i, label := rotateTarget(name, p)
+		// 旧密钥需要解密后以新的标签重新加密保存
+		ensureUnlocked(cfg)
+
+		if rotateRollback {
+			p = rollbackKey(cfg, name, p, i, label)
+			saveRotated(cfg, name, p, label)
+			fmt.Printf("%s 已回退到旧密钥: %s\n", green("✓"), keyTarget(name, label))
+			return
+		}
+
+		if n := countNonEmpty(rotateKey, rotateKeyCmd, rotateKeyFile); n == 0 {
+			fmt.Fprintf(os.Stderr, "%s 请使用 --key、--key-cmd 或 --key-file 指定新密钥\n", red("错误:"))
+			os.Exit(1)
+		} else if n > 1 {
+			fmt.Fprintf(os.Stderr, "%s --key、--key-cmd 和 --key-file 只能指定一个\n", red("错误:"))
+			os.Exit(1)
+		}
+		expiresAt, err := parseKeyExpiry(rotateExpires)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		old, hasOld := currentKey(cfg, name, p, i)
+		owner := rotateOwner
+		if owner == "" {
+			owner = old.Owner
+		}
+		entry := provider.APIKeyEntry{
+			Label:   label,
+			Key:     rotateKey,
+			KeyCmd:  rotateKeyCmd,
+			KeyFile: rotateKeyFile,
+			KeyMeta: provider.KeyMeta{CreatedAt: keyCreatedAt(), ExpiresAt: expiresAt, Owner: owner},
+		}
+
+		if !rotateNoTest {
+			newKey, err := config.ResolveKeyEntry(cfg, name, entry)
+			if err == nil && newKey == "" {
+				err = errors.New("API Key 为空")
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 获取新密钥失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Println("正在验证新密钥...")
+			result := probe.Probe(context.Background(), p, newKey)
+			switch {
+			case result.OK():
+				fmt.Printf("%s 新密钥验证通过 (%v)\n", green("✓"), result.Latency.Round(time.Millisecond))
+			case result.Class == probe.ClassAuth:
+				fmt.Fprintf(os.Stderr, "%s 新密钥被拒绝，未更换: %v\n", red("错误:"), result.AsError())
+				fmt.Fprintf(os.Stderr, "确认密钥无误时可使用 --no-test 跳过验证\n")
+				os.Exit(1)
+			default:
+				fmt.Fprintf(os.Stderr, "%s 新密钥测试未通过，仍继续更换: %v\n", yellow("警告:"), result.AsError())
+			}
+		}
+
+		now := time.Now()
+		p = config.PruneRetiredKeys(p, now)
+		retired := false
+		if hasOld && rotateGrace > 0 {
+			until := now.Add(rotateGrace).Truncate(time.Second)
+			if old.ExpiresAt.IsZero() || until.Before(old.ExpiresAt) {
+				old.ExpiresAt = until
+			}
+			if old.ExpiresAt.After(now) {
+				old.Label = config.RetiredLabel(label)
+				p = setRetiredKey(p, old)
+				retired = true
+			}
+		}
+
+		if i < 0 {
+			p.APIKey, p.APIKeyCmd, p.APIKeyFile = entry.Key, entry.KeyCmd, entry.KeyFile
+			p.KeyMeta = entry.KeyMeta
+		} else {
+			p.Keys[i] = entry
+		}
+		saveRotated(cfg, name, p, label)
+
+		fmt.Printf("%s 已更换密钥: %s\n", green("✓"), keyTarget(name, label))
+		if !expiresAt.IsZero() {
+			fmt.Printf("  过期时间: %s\n", expiresAt.Local().Format("2006-01-02 15:04"))
+		}
+		if retired {
+			fmt.Printf("  旧密钥保留至 %s，期间网关在新密钥失效时使用\n", old.ExpiresAt.Local().Format("2006-01-02 15:04"))
+			fmt.Println(gray(fmt.Sprintf("  如需回退: ccm rotate %s --rollback", rotateArgs(name, label))))
+		}
+	},
+}
+
+// rotateTarget 返回要更换的密钥在 keys 中的下标和标签，单个密钥时下标为 -1
+func rotateTarget(name string, p provider.Provider) (int, string) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if len(p.Keys) == 0 {
+		if rotateLabel != "" {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置多个密钥，无需指定 --label\n", red("错误:"), name)
+			os.Exit(1)
+		}
+		return -1, ""
+	}
+	if rotateLabel == "" {
+		if len(p.Keys) > 1 {
+			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 有 %d 个密钥，请使用 --label 指定要更换的密钥\n", red("错误:"), name, len(p.Keys))
+			os.Exit(1)
+		}
+		return 0, p.Keys[0].Label
+	}
+	i := p.FindKey(rotateLabel)
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 没有标签为 '%s' 的密钥\n", red("错误:"), name, rotateLabel)
+		os.Exit(1)
+	}
+	return i, rotateLabel
+}
+
+// currentKey 返回当前密钥 (已解密)，未在配置中保存密钥时返回 false
+func currentKey(cfg *config.Config, name string, p provider.Provider, i int) (provider.APIKeyEntry, bool) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	var (
+		e   provider.APIKeyEntry
+		err error
+	)
+	if i < 0 {
+		if p.APIKey == "" && p.APIKeyCmd == "" && p.APIKeyFile == "" {
+			return e, false
+		}
+		e = provider.APIKeyEntry{KeyCmd: p.APIKeyCmd, KeyFile: p.APIKeyFile, KeyMeta: p.KeyMeta}
+		e.Key, err = config.RevealAPIKey(cfg, name)
+	} else {
+		e = p.Keys[i]
+		e.Key, err = config.RevealKeyEntry(cfg, name, e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	return e, true
+}
+
+// rollbackKey 用保留的旧密钥替换当前密钥
+func rollbackKey(cfg *config.Config, name string, p provider.Provider, i int, label string) provider.Provider {
+	red := color.New(color.FgRed).SprintFunc()
+
+	p = config.PruneRetiredKeys(p, time.Now())
+	j := findRetiredKey(p, config.RetiredLabel(label))
+	if j < 0 {
+		fmt.Fprintf(os.Stderr, "%s %s 没有可回退的旧密钥 (未轮换过或已过期)\n", red("错误:"), keyTarget(name, label))
+		os.Exit(1)
+	}
+	e := p.RetiredKeys[j]
+	key, err := config.RevealKeyEntry(cfg, name, e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	p.RetiredKeys = append(p.RetiredKeys[:j:j], p.RetiredKeys[j+1:]...)
+
+	// 保留期限不是密钥本身的过期时间
+	e.Key, e.Label, e.ExpiresAt = key, label, time.Time{}
+	if i < 0 {
+		p.APIKey, p.APIKeyCmd, p.APIKeyFile = e.Key, e.KeyCmd, e.KeyFile
+		p.KeyMeta = e.KeyMeta
+	} else {
+		p.Keys[i] = e
+	}
+	return p
+}
+
+// findRetiredKey 返回指定标签的旧密钥下标，不存在时返回 -1
+func findRetiredKey(p provider.Provider, label string) int {
+	for i, k := range p.RetiredKeys {
+		if k.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// setRetiredKey 保存旧密钥，同一密钥只保留最近一次轮换下来的旧密钥
+func setRetiredKey(p provider.Provider, e provider.APIKeyEntry) provider.Provider {
+	if j := findRetiredKey(p, e.Label); j >= 0 {
+		p.RetiredKeys[j] = e
+		return p
+	}
+	p.RetiredKeys = append(p.RetiredKeys, e)
+	return p
+}
+
+// saveRotated 保存更换后的配置，并清除新旧密钥的吊销、耗尽标记
+func saveRotated(cfg *config.Config, name string, p provider.Provider, label string) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	cfg.Providers[name] = p
+	if err := config.Save(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "%s 保存配置失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	if label != "" {
+		_ = config.ResetKeyState(name, label)
+	}
+	_ = config.ResetKeyState(name, config.RetiredLabel(label))
+}
+
+// rotateArgs 返回更换指定密钥的 ccm rotate 参数
+func rotateArgs(name, label string) string {
+	if label == "" {
+		return name
+	}
+	return name + " --label " + label
+}
+
+// keyCreatedAt 返回新密钥的创建时间
+func keyCreatedAt() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// formatKeyMeta 显示密钥的创建时间、过期时间和负责人
+func formatKeyMeta(m provider.KeyMeta) string {
+	var parts []string
+	if !m.CreatedAt.IsZero() {
+		parts = append(parts, "创建于 "+m.CreatedAt.Local().Format("2006-01-02"))
+	}
+	if !m.ExpiresAt.IsZero() {
+		parts = append(parts, "过期 "+m.ExpiresAt.Local().Format("2006-01-02"))
+	}
+	if m.Owner != "" {
+		parts = append(parts, "负责人 "+m.Owner)
+	}
+	if len(parts) == 0 {
+		return "(未记录)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseKeyExpiry 解析密钥过期时间，支持 2026-12-31、RFC3339 和 90d (天数)
+func parseKeyExpiry(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Now().AddDate(0, 0, n).Truncate(time.Second), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的过期时间 '%s'，可使用 2026-12-31、RFC3339 时间或 90d", s)
+}
+
+// describeKeyWarning 返回密钥提醒的中文说明
+func describeKeyWarning(w config.KeyWarning) string {
+	target := keyTarget(w.Provider, w.Label)
+	date := w.Date.Local().Format("2006-01-02")
+
+	var msg string
+	switch w.Kind {
+	case config.KeyExpired:
+		msg = fmt.Sprintf("%s 的 API Key 已于 %s 过期", target, date)
+	case config.KeyExpiring:
+		if w.Days <= 1 {
+			msg = fmt.Sprintf("%s 的 API Key 将于 24 小时内过期 (%s)", target, w.Date.Local().Format("2006-01-02 15:04"))
+		} else {
+			msg = fmt.Sprintf("%s 的 API Key 将于 %d 天后过期 (%s)", target, w.Days, date)
+		}
+	default:
+		msg = fmt.Sprintf("%s 的 API Key 已使用 %d 天 (创建于 %s)，请按轮换策略更换", target, w.Days, date)
+	}
+	if w.Owner != "" {
+		msg += "，负责人: " + w.Owner
+	}
+	return msg
+}
+
+// printKeyWarnings 在标准错误输出密钥提醒和更换方法
+func printKeyWarnings(warnings []config.KeyWarning) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "%s %s\n", yellow("警告:"), describeKeyWarning(w))
+		fmt.Fprintf(os.Stderr, "  %s\n", gray("更换密钥: ccm rotate "+rotateArgs(w.Provider, w.Label)+" --key \"...\""))
+	}
+}
+
+func init() {
+	rotateCmd.Flags().StringVarP(&rotateLabel, "label", "l", "", "要更换的密钥标签 (配置了多个密钥时)")
+	rotateCmd.Flags().StringVarP(&rotateKey, "key", "k", "", "新的 API 密钥 (可用 ${ENV} 引用环境变量)")
+	rotateCmd.Flags().StringVar(&rotateKeyCmd, "key-cmd", "", "输出新 API 密钥的命令，取第一行")
+	rotateCmd.Flags().StringVar(&rotateKeyFile, "key-file", "", "保存新 API 密钥的文件")
+	rotateCmd.Flags().DurationVar(&rotateGrace, "grace", config.DefaultRetiredGrace, "旧密钥保留为备用的时长，0 表示不保留")
+	rotateCmd.Flags().StringVar(&rotateExpires, "expires", "", "新密钥的过期时间 (2026-12-31 或 90d)")
+	rotateCmd.Flags().StringVar(&rotateOwner, "owner", "", "新密钥的负责人，默认沿用旧密钥")
+	rotateCmd.Flags().BoolVar(&rotateRollback, "rollback", false, "回退到保留的旧密钥")
+	rotateCmd.Flags().BoolVar(&rotateNoTest, "no-test", false, "不验证新密钥")
+	rootCmd.AddCommand(rotateCmd)
+}
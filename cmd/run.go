@@ -7,116 +7,443 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"ccm/internal/config"
+	"ccm/internal/history"
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+var (
+	runProxyAddr  string
+	runModel      string
+	runSmallModel string
+	runTimeout    time.Duration
+	runEnv        []string
+	runDryRun     bool
+	runReveal     bool
+	runSupervise  bool
+
+	// runSuperviseSet 命令行是否指定了 --supervise（优先于配置）
+	runSuperviseSet bool
+)
+
+// defaultRunTimeout Claude Code 的 API 请求超时时间
+const defaultRunTimeout = 5 * time.Minute
+
 var runCmd = &cobra.Command{
 	Use:     "run [name]",
 	Aliases: []string{"r"},
 	Short:   "使用指定供应商启动 Claude Code",
 	Long: `使用指定供应商启动 Claude Code
 
-如果不指定供应商名称，则使用默认供应商。
-使用 'ccm default <name>' 设置默认供应商。
+如果不指定供应商名称，则从当前目录向上查找项目配置 .ccm.yaml，
+未找到时使用默认供应商 (使用 'ccm default <name>' 设置)。
+
+项目配置示例 (可提交到仓库，不要写入 API Key):
+  provider: deepseek
+  model: deepseek-reasoner
+  env:
+    DISABLE_TELEMETRY: "1"
 
 示例:
-  ccm run              使用默认供应商启动
+  ccm run              使用项目配置或默认供应商启动
   ccm run doubao       使用豆包启动
-  ccm run deepseek     使用 DeepSeek 启动`,
-	Args: cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		red := color.New(color.FgRed).SprintFunc()
-		cyan := color.New(color.FgCyan).SprintFunc()
+  ccm run deepseek     使用 DeepSeek 启动
+  ccm run --proxy      通过本地网关 (ccm serve) 启动，密钥不进入 Claude 进程
+  ccm run kimi -- --resume          将 -- 之后的参数传给 claude
+  ccm run kimi --dry-run            只显示将要使用的环境变量和命令，不启动
+  ccm run kimi --supervise          以子进程运行 claude，结束后显示用量并执行会话钩子
 
-		var name string
+本次启动覆盖 (不修改已保存的配置):
+  ccm run deepseek --model deepseek-reasoner    临时使用其他模型
+  ccm run glm --small-model glm-4.5-air         临时指定后台任务使用的小模型
+  ccm run qwen --timeout 10m                    API 请求超时时间 (默认 5m)
+  ccm run kimi --env DISABLE_TELEMETRY=1        额外的环境变量 (可重复)`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if n := cmd.ArgsLenAtDash(); n >= 0 {
+			args = args[:n]
+		}
+		return cobra.MaximumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var claudeArgs []string
+		if n := cmd.ArgsLenAtDash(); n >= 0 {
+			args, claudeArgs = args[:n], args[n:]
+		}
+		name := ""
 		if len(args) > 0 {
 			name = args[0]
+		}
+		runSuperviseSet = cmd.Flags().Changed("supervise")
+		runProvider(history.SourceRun, name, claudeArgs)
+	},
+}
+
+// runProvider 使用指定供应商启动 Claude Code，name 为空时使用项目配置或默认供应商
+// claudeArgs 原样传给 claude，source 为启动来源（记录到启动历史）
+func runProvider(source, name string, claudeArgs []string) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	explicit := name != ""
+	t := loadLaunchProvider(name, runProxyAddr != "")
+	name, cfg, p, apiKey := t.Name, t.Config, t.Provider, t.APIKey
+	if !explicit && !runDryRun {
+		if t.Project != nil {
+			fmt.Printf("使用项目供应商: %s %s\n\n", cyan(name), gray("("+shortenHome(t.Project.Path)+")"))
 		} else {
-			// 使用默认供应商
-			name = config.GetDefault()
-			if name == "" {
-				fmt.Fprintf(os.Stderr, "%s 未指定供应商，且未设置默认供应商\n", red("错误:"))
-				fmt.Fprintf(os.Stderr, "\n使用方法:\n")
-				fmt.Fprintf(os.Stderr, "  %s        指定供应商启动\n", cyan("ccm run <name>"))
-				fmt.Fprintf(os.Stderr, "  %s  设置默认供应商\n", cyan("ccm default <name>"))
-				os.Exit(1)
-			}
 			fmt.Printf("使用默认供应商: %s\n\n", cyan(name))
 		}
+	}
+	// 经网关连接时由网关选择密钥
+	if t.KeyLabel != "" && !runDryRun {
+		fmt.Printf("使用密钥: %s %s\n\n", cyan(t.KeyLabel), gray("("+string(p.EffectiveKeyStrategy())+")"))
+	}
 
-		// 加载配置
-		cfg, err := config.Load()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
-			os.Exit(1)
-		}
+	// 提醒即将过期或超过轮换期限的密钥
+	if warnings := cfg.KeyWarnings(name, time.Now()); len(warnings) > 0 {
+		printKeyWarnings(warnings)
+		fmt.Fprintln(os.Stderr)
+	}
 
-		// 检查供应商是否已配置
-		p, ok := cfg.Providers[name]
-		if !ok {
-			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
-			fmt.Fprintf(os.Stderr, "请先运行: ccm add %s --key \"你的API密钥\"\n", name)
-			os.Exit(1)
+	// 检查费用预算
+	checkBudget(cfg, name)
+
+	// 本次启动的覆盖项
+	extraEnv, err := parseEnvOverrides(runEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	if runTimeout <= 0 {
+		fmt.Fprintf(os.Stderr, "%s 无效的超时时间 '%s'\n", red("错误:"), runTimeout)
+		os.Exit(1)
+	}
+	// 网关按已保存的配置映射模型，未声明的模型会被替换
+	if runProxyAddr != "" && runModel != "" && p.ResolveModel(runModel) != runModel {
+		fmt.Fprintf(os.Stderr, "%s 模型 '%s' 不在供应商的模型列表中，网关会将其映射为 '%s'\n", yellow("警告:"), runModel, p.ResolveModel(runModel))
+		fmt.Fprintf(os.Stderr, "可先添加到模型列表: %s\n\n", cyan(fmt.Sprintf("ccm edit %s --models <模型列表>", name)))
+	}
+	p = applyModelOverrides(p, runModel, runSmallModel)
+
+	// OpenAI 协议和 query 认证的供应商需要经本地网关转换
+	if p.NeedsProxy() && runProxyAddr == "" {
+		reason := "使用 OpenAI 协议"
+		if p.EffectiveProtocol() != provider.ProtocolOpenAI {
+			reason = "使用查询参数认证"
 		}
+		fmt.Fprintf(os.Stderr, "%s 供应商 '%s' %s，Claude Code 无法直接连接\n", red("错误:"), name, reason)
+		fmt.Fprintln(os.Stderr, "💡 解决方案:")
+		fmt.Fprintf(os.Stderr, "   1. 启动本地网关: %s\n", cyan("ccm serve"))
+		fmt.Fprintf(os.Stderr, "   2. 通过网关启动: %s\n", cyan(fmt.Sprintf("ccm run %s --proxy", name)))
+		os.Exit(1)
+	}
+
+	gw := connectGateway(runProxyAddr, name, t.Explicit)
+	env := providerEnv(name, p, apiKey, gw, runTimeout, extraEnv)
+	claudeBin := findClaudeBin()
+	argv := append([]string{"claude"}, claudeArgs...)
+
+	if runDryRun {
+		printDryRun(name, p, claudeBin, argv, env, apiKey, gw.Token)
+		return
+	}
+
+	// 检查 npm 是否安装
+	if !hasNPM() {
+		fmt.Fprintln(os.Stderr, red("错误: 未找到 npm 命令"))
+		fmt.Fprintln(os.Stderr, "💡 解决方案:")
+		fmt.Fprintln(os.Stderr, "   - macOS: brew install node")
+		fmt.Fprintln(os.Stderr, "   - Ubuntu/Debian: sudo apt install npm")
+		fmt.Fprintln(os.Stderr, "   - Fedora: sudo dnf install nodejs")
+		os.Exit(1)
+	}
+
+	// 查找 claude 可执行文件
+	if claudeBin == "" {
+		fmt.Fprintln(os.Stderr, red("错误: 未找到 claude 命令"))
+		fmt.Fprintln(os.Stderr, "💡 解决方案:")
+		fmt.Fprintln(os.Stderr, "   全局安装: npm install -g @anthropic-ai/claude-code")
+		fmt.Fprintln(os.Stderr, "   本地安装: cd ~/claude-model && npm install @anthropic-ai/claude-code")
+		os.Exit(1)
+	}
+
+	// 设置独立的配置目录
+	if err := os.MkdirAll(config.GetClaudeConfigDir(name), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "%s 创建配置目录失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+
+	// 记录启动历史
+	dir, _ := os.Getwd()
+	timeout := ""
+	if runTimeout != defaultRunTimeout {
+		timeout = runTimeout.String()
+	}
+	historyID, _ := history.Record(history.Entry{
+		Provider:   name,
+		Model:      p.Model,
+		SmallModel: runSmallModel,
+		Dir:        dir,
+		Args:       claudeArgs,
+		EnvKeys:    envKeys(extraEnv),
+		Timeout:    timeout,
+		Source:     source,
+		Proxy:      runProxyAddr,
+	})
+
+	// 设置环境变量
+	applyEnv(env)
 
-		// 获取 API Key (支持环境变量)
-		apiKey := config.GetEffectiveAPIKey(name)
-		if apiKey == "" {
-			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未设置 API Key\n", red("错误:"), name)
-			fmt.Fprintf(os.Stderr, "请先运行: ccm add %s --key \"你的API密钥\"\n", name)
-			fmt.Fprintf(os.Stderr, "或设置环境变量: export CCM_API_KEY_%s=\"your-key\"\n", strings.ToUpper(name))
+	printRunOverrides(p, extraEnv)
+
+	// 监管模式: 以子进程运行 claude，结束后显示用量并执行会话钩子
+	supervise := cfg.Supervise
+	if runSuperviseSet {
+		supervise = runSupervise
+	}
+	if supervise {
+		session := sessionResult{Provider: name, Model: p.Model, Start: time.Now()}
+		code, err := superviseClaude(claudeBin, argv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 启动 claude 失败: %v\n", red("错误:"), err)
 			os.Exit(1)
 		}
+		session.End = time.Now()
+		session.ExitCode = code
+		if historyID != "" {
+			_ = history.Finish(historyID, session.End, code)
+		}
+		printSessionSummary(cfg, session)
+		runPostSessionHooks(cfg.Hooks.PostSession, session)
+		os.Exit(code)
+	}
+
+	// 使用 syscall.Exec 替换当前进程
+	err = syscall.Exec(claudeBin, argv, os.Environ())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 启动 claude 失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+}
+
+// launchTarget 要启动的供应商
+type launchTarget struct {
+	Name     string
+	Config   *config.Config
+	Provider provider.Provider // 已应用项目配置的模型和环境变量
+	APIKey   string
+	KeyLabel string          // 配置了多个密钥时选中的密钥标签
+	Project  *config.Project // 生效的项目配置，未使用时为 nil
+	Explicit bool            // 是否由命令行或项目配置指定了供应商
+}
+
+// loadLaunchProvider 加载要启动的供应商和 API Key
+// name 为空时优先使用当前目录向上查找到的项目配置 (.ccm.yaml)，其次使用默认供应商
+// 供应商未配置或缺少 API Key 时输出提示并退出
+// viaProxy 为 true 时经本地网关连接，由网关选择和获取密钥，这里不再获取
+func loadLaunchProvider(name string, viaProxy bool) launchTarget {
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	t := launchTarget{Name: name, Explicit: name != ""}
 
-		// 检查 npm 是否安装
-		if !hasNPM() {
-			fmt.Fprintln(os.Stderr, red("错误: 未找到 npm 命令"))
-			fmt.Fprintln(os.Stderr, "💡 解决方案:")
-			fmt.Fprintln(os.Stderr, "   - macOS: brew install node")
-			fmt.Fprintln(os.Stderr, "   - Ubuntu/Debian: sudo apt install npm")
-			fmt.Fprintln(os.Stderr, "   - Fedora: sudo dnf install nodejs")
+	// 查找项目配置
+	if dir, err := os.Getwd(); err == nil {
+		project, err := config.FindProject(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 读取项目配置失败: %v\n", red("错误:"), err)
 			os.Exit(1)
 		}
+		// 命令行指定了其他供应商时忽略项目配置
+		if project != nil && (name == "" || name == project.Provider) {
+			t.Name = project.Provider
+			t.Project = project
+			t.Explicit = true
+		}
+	}
 
-		// 查找 claude 可执行文件
-		claudeBin := findClaudeBin()
-		if claudeBin == "" {
-			fmt.Fprintln(os.Stderr, red("错误: 未找到 claude 命令"))
-			fmt.Fprintln(os.Stderr, "💡 解决方案:")
-			fmt.Fprintln(os.Stderr, "   全局安装: npm install -g @anthropic-ai/claude-code")
-			fmt.Fprintln(os.Stderr, "   本地安装: cd ~/claude-model && npm install @anthropic-ai/claude-code")
+	if t.Name == "" {
+		// 使用默认供应商
+		t.Name = config.GetDefault()
+		if t.Name == "" {
+			fmt.Fprintf(os.Stderr, "%s 未指定供应商，且未设置默认供应商\n", red("错误:"))
+			fmt.Fprintf(os.Stderr, "\n使用方法:\n")
+			fmt.Fprintf(os.Stderr, "  %s        指定供应商启动\n", cyan("ccm run <name>"))
+			fmt.Fprintf(os.Stderr, "  %s  设置默认供应商\n", cyan("ccm default <name>"))
 			os.Exit(1)
 		}
+	}
+	name = t.Name
 
-		// 设置环境变量
-		os.Setenv("ANTHROPIC_AUTH_TOKEN", apiKey)
-		os.Setenv("ANTHROPIC_BASE_URL", p.BaseURL)
-		os.Setenv("ANTHROPIC_MODEL", p.Model)
-		os.Setenv("API_TIMEOUT_MS", "300000")
-
-		// 设置独立的配置目录
-		home, _ := os.UserHomeDir()
-		configDir := filepath.Join(home, "claude-model", "configs", ".claude-"+name)
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "%s 创建配置目录失败: %v\n", red("错误:"), err)
-			os.Exit(1)
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	t.Config = cfg
+
+	// 检查供应商是否已配置
+	p, ok := cfg.Providers[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), name)
+		if t.Project != nil {
+			fmt.Fprintf(os.Stderr, "该供应商由项目配置 %s 指定\n", shortenHome(t.Project.Path))
 		}
-		os.Setenv("CLAUDE_CONFIG_DIR", configDir)
+		fmt.Fprintf(os.Stderr, "请先运行: ccm add %s --key \"你的API密钥\"\n", name)
+		os.Exit(1)
+	}
+	if t.Project != nil {
+		p = t.Project.Apply(p)
+	}
+	t.Provider = p
+	if viaProxy {
+		return t
+	}
+
+	// 获取 API Key (支持环境变量、命令和文件，加密保存时需要先解锁密钥库)
+	src := config.FindKeySource(cfg, name)
+	if src == nil {
+		fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未设置 API Key\n", red("错误:"), name)
+		fmt.Fprintf(os.Stderr, "请先运行: ccm add %s --key \"你的API密钥\"\n", name)
+		fmt.Fprintf(os.Stderr, "或设置环境变量: export CCM_API_KEY_%s=\"your-key\"\n", strings.ToUpper(name))
+		os.Exit(1)
+	}
+	if config.NeedsVault(src) {
+		ensureUnlocked(cfg)
+	}
+	t.APIKey, src, err = config.ResolveAPIKey(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 获取供应商 '%s' 的 API Key 失败: %v\n", red("错误:"), name, err)
+		os.Exit(1)
+	}
+	if pool, ok := src.(*config.KeyPool); ok {
+		t.KeyLabel = pool.Label()
+	}
 
-		// 获取剩余参数传递给 claude
-		claudeArgs := cmd.Flags().Args()
+	return t
+}
 
-		// 使用 syscall.Exec 替换当前进程
-		err = syscall.Exec(claudeBin, append([]string{"claude"}, claudeArgs...), os.Environ())
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s 启动 claude 失败: %v\n", red("错误:"), err)
-			os.Exit(1)
+// gateway 经本地网关连接时的 Base URL 和访问令牌，URL 为空表示直连
+type gateway struct {
+	URL   string
+	Token string
+}
+
+// connectGateway 返回经本地网关连接时的地址和令牌，addr 为空表示直连
+// 未指定供应商时使用网关根路径，跟随默认供应商切换
+func connectGateway(addr, name string, explicit bool) gateway {
+	red := color.New(color.FgRed).SprintFunc()
+
+	if addr == "" {
+		return gateway{}
+	}
+	token, err := proxy.LoadToken(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	if explicit {
+		return gateway{URL: "http://" + addr + "/providers/" + name, Token: token}
+	}
+	return gateway{URL: "http://" + addr, Token: token}
+}
+
+// printDryRun 显示将要执行的 claude 命令和环境变量，不实际启动
+func printDryRun(name string, p provider.Provider, claudeBin string, argv []string, env []provider.EnvVar, secrets ...string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if claudeBin == "" {
+		claudeBin = yellow("(未找到 claude 命令)")
+	}
+	if !runReveal {
+		env = maskEnv(env, secrets...)
+	}
+
+	fmt.Println()
+	fmt.Printf("  供应商:     %s (%s)\n", cyan(name), p.DisplayName)
+	fmt.Printf("  可执行文件: %s\n", claudeBin)
+	fmt.Printf("  参数:       %s\n", strings.Join(argv, " "))
+	fmt.Println("  环境变量:")
+	fmt.Printf("    %s\n", gray("unset "+strings.Join(credentialVars, " ")))
+	for _, e := range env {
+		value := strings.ReplaceAll(e.Value, "\n", `\n`)
+		fmt.Printf("    %s=%s\n", e.Key, value)
+	}
+	fmt.Println()
+	if !runReveal {
+		fmt.Println(gray("密钥已隐藏，使用 --reveal 显示"))
+	}
+}
+
+// applyModelOverrides 返回应用了本次启动模型覆盖的供应商副本
+// 小模型同时用于 haiku 角色和 small_fast（后台任务）
+func applyModelOverrides(p provider.Provider, model, smallModel string) provider.Provider {
+	if model != "" {
+		p.Model = model
+	}
+	if smallModel != "" {
+		roles := p.EffectiveRoles()
+		roles.Haiku = smallModel
+		roles.SmallFast = smallModel
+		p.Roles = &roles
+	}
+	return p
+}
+
+// parseEnvOverrides 解析 KEY=VALUE 形式的环境变量
+func parseEnvOverrides(pairs []string) ([]provider.EnvVar, error) {
+	var env []provider.EnvVar
+	for _, kv := range pairs {
+		key, value, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("无效的环境变量 '%s'，格式: KEY=VALUE", kv)
 		}
-	},
+		env = append(env, provider.EnvVar{Key: key, Value: value})
+	}
+	return env, nil
+}
+
+// envKeys 返回环境变量名，启动历史只记录变量名，不保存可能是密钥的值
+func envKeys(env []provider.EnvVar) []string {
+	var keys []string
+	for _, e := range env {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+// printRunOverrides 显示本次启动的覆盖项，提醒它们不会保存到配置
+func printRunOverrides(p provider.Provider, extraEnv []provider.EnvVar) {
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	var parts []string
+	if runModel != "" {
+		parts = append(parts, "模型="+p.Model)
+	}
+	if runSmallModel != "" {
+		parts = append(parts, "小模型="+runSmallModel)
+	}
+	if runTimeout != defaultRunTimeout {
+		parts = append(parts, "超时="+runTimeout.String())
+	}
+	for _, env := range extraEnv {
+		parts = append(parts, env.Key)
+	}
+	if len(parts) > 0 {
+		fmt.Println(gray("本次启动覆盖: " + strings.Join(parts, ", ")))
+	}
 }
 
 // findClaudeBin 查找 claude 可执行文件
@@ -145,5 +472,14 @@ func hasNPM() bool {
 }
 
 func init() {
+	runCmd.Flags().StringVar(&runProxyAddr, "proxy", "", "通过本地网关启动 (默认地址 "+proxy.DefaultAddr+")")
+	runCmd.Flags().Lookup("proxy").NoOptDefVal = proxy.DefaultAddr
+	runCmd.Flags().StringVarP(&runModel, "model", "m", "", "本次启动使用的模型 (不修改配置)")
+	runCmd.Flags().StringVar(&runSmallModel, "small-model", "", "本次启动的小模型，用于 haiku 和后台任务 (不修改配置)")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", defaultRunTimeout, "API 请求超时时间 (如 10m)")
+	runCmd.Flags().StringArrayVarP(&runEnv, "env", "e", nil, "额外的环境变量 KEY=VALUE (可重复)")
+	runCmd.Flags().BoolVar(&runDryRun, "dry-run", false, "只显示将要执行的命令和环境变量，不启动")
+	runCmd.Flags().BoolVar(&runReveal, "reveal", false, "--dry-run 时显示完整的密钥")
+	runCmd.Flags().BoolVar(&runSupervise, "supervise", false, "监管模式: 以子进程运行 claude (配置 supervise: true 时默认开启)")
 	rootCmd.AddCommand(runCmd)
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/proxy"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr     string
+	serveProvider string
+	serveTimeout  time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动本地 Anthropic 兼容网关",
+	Long: `启动本地 Anthropic 兼容网关
+
+网关接收 Anthropic Messages API 请求，转发给已配置的供应商，并注入真实的 API Key。
+Claude Code 只需连接本地地址，无需在进程中暴露密钥。
+
+路由规则:
+  /v1/...                   转发到 --provider 指定的供应商 (未指定时使用当前默认供应商)
+  /providers/<name>/v1/...  固定转发到指定供应商
+
+未指定 --provider 时，每次请求都会读取当前默认供应商，
+执行 'ccm default <name>' 即可切换，无需重启 Claude 会话。
+
+网关只监听本机回环地址，每次启动生成新的访问令牌，请求需通过
+Authorization 或 x-api-key 携带该令牌。'ccm run --proxy'、'ccm env --proxy'
+和 'ccm exec --proxy' 会自动读取令牌，网关重启后需重新启动 Claude 会话。
+
+配置故障转移顺序后 ('ccm fallback <name>...')，供应商返回 429/5xx、
+超时或网络错误时，请求会自动重试下一个供应商。
+
+示例:
+  ccm serve                       使用默认供应商
+  ccm serve --provider deepseek   固定使用 DeepSeek
+  ccm run --proxy                 通过本地网关启动 Claude Code`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+		cyan := color.New(color.FgCyan).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
+
+		if err := proxy.CheckListenAddr(serveAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		if serveProvider != "" {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			if _, ok := cfg.Providers[serveProvider]; !ok {
+				fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未配置\n", red("错误:"), serveProvider)
+				os.Exit(1)
+			}
+		}
+
+		// 网关进程解锁后在运行期间保持解锁
+		if cfg, err := config.Load(); err == nil {
+			ensureUnlocked(cfg)
+		}
+
+		// 每次启动生成新的访问令牌，只有读取到令牌的本机 ccm 会话可以使用网关
+		token, err := proxy.NewToken(serveAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 生成网关访问令牌失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		srv := proxy.New(serveProvider)
+		srv.Timeout = serveTimeout
+		srv.Token = token
+		srv.OnServed = func(rec proxy.Record) {
+			status := green(rec.Status)
+			if rec.Status >= 400 {
+				status = red(rec.Status)
+			}
+			line := fmt.Sprintf("%s %s %-10s %s %s %s",
+				gray(time.Now().Format("15:04:05")),
+				status,
+				cyan(keyTarget(rec.Provider, rec.Key)),
+				rec.Method,
+				rec.Path,
+				gray(rec.Latency.Round(time.Millisecond)),
+			)
+			if rec.Err != nil {
+				line += " " + red(rec.Err.Error())
+			}
+			for _, a := range rec.Attempts {
+				reason := fmt.Sprint(a.Status)
+				if a.Err != nil {
+					reason = a.Err.Error()
+				}
+				line += "\n" + gray(fmt.Sprintf("         ↳ %s 失败 (%s)，已切换", keyTarget(a.Provider, a.Key), reason))
+			}
+			fmt.Println(line)
+		}
+
+		target := serveProvider
+		if target == "" {
+			target = "默认供应商"
+		}
+		fmt.Printf("%s 本地网关已启动: %s\n", green("✓"), cyan("http://"+serveAddr))
+		fmt.Printf("  转发目标: %s\n", target)
+		if cfg, err := config.Load(); err == nil && len(cfg.Fallback) > 0 {
+			fmt.Printf("  故障转移: %s\n", strings.Join(cfg.Fallback, " -> "))
+		}
+		fmt.Printf("  %s\n", gray(fmt.Sprintf("export ANTHROPIC_BASE_URL=http://%s", serveAddr)))
+		fmt.Printf("  %s\n\n", gray(fmt.Sprintf("export ANTHROPIC_AUTH_TOKEN=\"$(cat %s)\"", shortenHome(proxy.TokenFile(serveAddr)))))
+
+		if err := srv.ListenAndServe(serveAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 网关启动失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+	},
+}
+
+// keyTarget 日志中的转发目标，配置了多个密钥时附加密钥标签
+func keyTarget(name, label string) string {
+	if label == "" {
+		return name
+	}
+	return name + "/" + label
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveAddr, "addr", "a", proxy.DefaultAddr, "监听地址")
+	serveCmd.Flags().StringVarP(&serveProvider, "provider", "p", "", "固定使用的供应商 (默认跟随默认供应商)")
+	serveCmd.Flags().DurationVar(&serveTimeout, "timeout", proxy.DefaultTimeout, "单个供应商等待响应的超时时间，超时后切换")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"ccm/internal/config"
 	"ccm/internal/provider"
@@ -35,7 +39,7 @@ var showCmd = &cobra.Command{
 		// 获取用户配置
 		cfg, _ := config.Load()
 		p, hasProvider := cfg.Providers[name]
-		configured := hasProvider && p.APIKey != ""
+		configured := hasProvider && p.HasAPIKey()
 
 		status := green("已配置")
 		if !configured {
@@ -49,7 +53,47 @@ var showCmd = &cobra.Command{
 		fmt.Printf("  %s 显示名称:   %s\n", gray("├"), preset.DisplayName)
 		fmt.Printf("  %s 状态:       %s\n", gray("├"), status)
 		fmt.Printf("  %s 模型:       %s\n", gray("├"), yellow(getModelOrDefault(name, cfg)))
+		if roles := getRolesOrDefault(name, cfg); !roles.Empty() {
+			fmt.Printf("  %s 角色映射:   %s\n", gray("├"), formatRoles(roles))
+		}
+		if models := getModelsOrDefault(name, cfg); len(models) > 0 {
+			fmt.Printf("  %s 可用模型:   %s\n", gray("├"), strings.Join(models, ", "))
+		}
 		fmt.Printf("  %s API URL:    %s\n", gray("├"), getBaseURLOrDefault(name, cfg))
+		fmt.Printf("  %s 协议:       %s\n", gray("├"), getProtocolOrDefault(name, cfg))
+		fmt.Printf("  %s 认证:       %s\n", gray("├"), getAuthOrDefault(name, cfg))
+		if src := config.FindKeySource(cfg, name); src != nil {
+			fmt.Printf("  %s 密钥来源:   %s\n", gray("├"), config.DescribeKeySource(src))
+		}
+		if hasProvider && len(p.Keys) > 0 {
+			fmt.Printf("  %s 密钥:       %s\n", gray("├"), formatKeyLabels(name, p))
+		}
+		if hasProvider && len(p.Keys) == 0 && !p.KeyMeta.IsZero() {
+			fmt.Printf("  %s 密钥信息:   %s\n", gray("├"), formatKeyMeta(p.KeyMeta))
+		}
+		if hasProvider && len(p.RetiredKeys) > 0 {
+			fmt.Printf("  %s 旧密钥:     %s\n", gray("├"), formatRetiredKeys(p))
+		}
+		for _, w := range cfg.KeyWarnings(name, time.Now()) {
+			fmt.Printf("  %s 轮换提醒:   %s\n", gray("├"), yellow(describeKeyWarning(w)))
+		}
+		if pricing, ok := getPricingOrDefault(name, cfg); ok {
+			fmt.Printf("  %s 单价:       %s\n", gray("├"), formatPricing(pricing))
+		}
+		if hasProvider {
+			for _, model := range slices.Sorted(maps.Keys(p.ModelPrices)) {
+				fmt.Printf("  %s 单价 (%s): %s\n", gray("├"), model, formatPricing(p.ModelPrices[model]))
+			}
+		}
+		if hasProvider && len(p.Env) > 0 {
+			fmt.Printf("  %s 环境变量:   %s\n", gray("├"), formatEnv(p.ExtraEnv()))
+		}
+		if hasProvider && len(p.Headers) > 0 {
+			fmt.Printf("  %s 请求头:     %s\n", gray("├"), formatHeaderNames(p))
+		}
+		if b, ok := cfg.Budgets[name]; ok {
+			fmt.Printf("  %s 预算:       %s\n", gray("├"), describeBudget(b, cfg.BudgetCurrency()))
+		}
 		fmt.Printf("  %s 获取 Key:   %s\n", gray("└"), preset.KeyURL)
 
 		if configured {
@@ -72,6 +116,20 @@ func getModelOrDefault(name string, cfg *config.Config) string {
 	return provider.Presets[name].Model
 }
 
+func getModelsOrDefault(name string, cfg *config.Config) []string {
+	if p, ok := cfg.Providers[name]; ok && len(p.Models) > 0 {
+		return p.Models
+	}
+	return provider.Presets[name].Models
+}
+
+func getRolesOrDefault(name string, cfg *config.Config) provider.ModelRoles {
+	if p, ok := cfg.Providers[name]; ok {
+		return p.EffectiveRoles()
+	}
+	return provider.Provider{Name: name}.EffectiveRoles()
+}
+
 func getBaseURLOrDefault(name string, cfg *config.Config) string {
 	if p, ok := cfg.Providers[name]; ok && p.BaseURL != "" {
 		return p.BaseURL
@@ -79,6 +137,61 @@ func getBaseURLOrDefault(name string, cfg *config.Config) string {
 	return provider.Presets[name].BaseURL
 }
 
+func getProtocolOrDefault(name string, cfg *config.Config) provider.Protocol {
+	if p, ok := cfg.Providers[name]; ok {
+		return p.EffectiveProtocol()
+	}
+	return provider.Presets[name].EffectiveProtocol()
+}
+
+func getAuthOrDefault(name string, cfg *config.Config) string {
+	if p, ok := cfg.Providers[name]; ok {
+		return p.AuthLabel()
+	}
+	return provider.Presets[name].AuthLabel()
+}
+
+func getPricingOrDefault(name string, cfg *config.Config) (provider.Pricing, bool) {
+	if p, ok := cfg.Providers[name]; ok {
+		return p.EffectivePricing()
+	}
+	return provider.Presets[name].EffectivePricing()
+}
+
 func init() {
 	rootCmd.AddCommand(showCmd)
 }
+
+// formatRetiredKeys 显示轮换下来的旧密钥及其保留期限
+func formatRetiredKeys(p provider.Provider) string {
+	now := time.Now()
+	parts := make([]string, len(p.RetiredKeys))
+	for i, k := range p.RetiredKeys {
+		switch {
+		case k.ExpiresAt.IsZero():
+			parts[i] = k.Label
+		case now.Before(k.ExpiresAt):
+			parts[i] = fmt.Sprintf("%s (保留至 %s)", k.Label, k.ExpiresAt.Local().Format("01-02 15:04"))
+		default:
+			parts[i] = k.Label + " (已过期)"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatKeyLabels 显示多个密钥的标签，标注已吊销或耗尽的密钥
+func formatKeyLabels(name string, p provider.Provider) string {
+	states := config.KeyStates(name)
+	now := time.Now()
+	labels := make([]string, len(p.Keys))
+	for i, k := range p.Keys {
+		labels[i] = k.Label
+		switch s := states[k.Label]; {
+		case s.Status == config.KeyRevoked:
+			labels[i] += " (已吊销)"
+		case !s.Available(now):
+			labels[i] += " (已耗尽)"
+		}
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/usage"
+
+	"github.com/fatih/color"
+)
+
+// sessionResult 监管模式下一次 Claude Code 会话的结果
+type sessionResult struct {
+	Provider string
+	Model    string
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+}
+
+// Duration 返回会话时长
+func (s sessionResult) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// superviseClaude 以子进程方式运行 claude 并等待其退出
+// 子进程与 ccm 共享终端: 终端产生的 SIGINT/SIGQUIT 会直接送达子进程，ccm 只需忽略；
+// SIGTERM/SIGHUP 通常只发给 ccm，需要转发给子进程
+func superviseClaude(bin string, argv []string) (int, error) {
+	child := exec.Command(bin, argv[1:]...)
+	child.Args = argv
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGTERM || sig == syscall.SIGHUP {
+					_ = child.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+	return exitCode(child.ProcessState, err)
+}
+
+// exitCode 返回子进程的退出码，被信号终止时按 shell 惯例返回 128+信号值
+func exitCode(state *os.ProcessState, err error) (int, error) {
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, err
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), nil
+	}
+	return state.ExitCode(), nil
+}
+
+// printSessionSummary 显示会话时长、退出码和本次会话的用量
+func printSessionSummary(cfg *config.Config, s sessionResult) {
+	gray := color.New(color.FgHiBlack).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+
+	fmt.Println()
+	fmt.Printf("%s %s  用时 %s  退出码 %d\n", gray("会话结束:"), cyan(s.Provider), s.Duration().Round(time.Second), s.ExitCode)
+
+	// 转录中的时间戳可能只精确到秒
+	since := s.Start.Truncate(time.Second)
+	entries, err := usage.Scan(s.Provider, since)
+	if err != nil {
+		return
+	}
+	var session []usage.Entry
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			session = append(session, e)
+		}
+	}
+	if len(session) == 0 {
+		return
+	}
+
+	pricer := usage.NewPricer(cfg)
+	_, total := usage.Summarize(session, usage.ByProvider, pricer)
+	fmt.Printf("%s %d 次请求  %s tokens  费用 %s\n",
+		gray("本次用量:"), total.Requests, formatTokens(total.Tokens()), formatCost(total, pricer.Currency()))
+}
+
+// runPostSessionHooks 依次执行会话结束钩子，会话信息通过 CCM_* 环境变量传入
+// 钩子不会收到供应商的 API Key、API 地址和请求头
+func runPostSessionHooks(hooks []string, s sessionResult) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(connectionVars, key) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		"CCM_PROVIDER="+s.Provider,
+		"CCM_MODEL="+s.Model,
+		"CCM_EXIT_CODE="+strconv.Itoa(s.ExitCode),
+		"CCM_STARTED_AT="+s.Start.Format(time.RFC3339),
+		"CCM_ENDED_AT="+s.End.Format(time.RFC3339),
+		"CCM_DURATION_SEC="+strconv.Itoa(int(s.Duration().Seconds())),
+	)
+	for _, hook := range hooks {
+		c := exec.Command("sh", "-c", hook)
+		c.Stdin = os.Stdin
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		c.Env = env
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s 会话钩子 '%s' 执行失败: %v\n", yellow("警告:"), hook, err)
+		}
+	}
+}
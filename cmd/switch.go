@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"ccm/internal/config"
+	"ccm/internal/history"
 	"ccm/internal/ui"
 
 	"github.com/fatih/color"
@@ -60,7 +61,7 @@ var switchCmd = &cobra.Command{
 
 		// 检查是否已配置
 		p, exists := cfg.Providers[selectedName]
-		if !exists || (p.APIKey == "" && config.GetEnvAPIKey(selectedName) == "") {
+		if !exists || (!p.HasAPIKey() && config.GetEnvAPIKey(selectedName) == "") {
 			fmt.Printf("\n%s 供应商 '%s' 未配置\n", red("错误:"), selectedName)
 			fmt.Printf("请先配置: ccm add %s --key \"your-api-key\"\n", selectedName)
 			os.Exit(1)
@@ -68,11 +69,7 @@ var switchCmd = &cobra.Command{
 
 		fmt.Printf("\n正在启动 Claude Code (%s)...\n", selectedName)
 
-		// 构建并执行 run 命令的参数
-		runArgs := []string{"run", selectedName}
-
-		// 直接调用 runCmd 的 Run 函数
-		runCmd.Run(cmd, runArgs)
+		runProvider(history.SourceSwitch, selectedName, nil)
 	},
 }
 
@@ -1,31 +1,64 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"ccm/internal/config"
+	"ccm/internal/probe"
+	"ccm/internal/provider"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+var (
+	deepTest    bool
+	testAll     bool
+	testWorkers int
+)
+
 var testCmd = &cobra.Command{
-	Use:   "test <name>",
+	Use:   "test <name> | --all",
 	Short: "测试供应商 API 连接",
 	Long: `测试供应商 API 连接是否正常
 
+使用配置的模型发送一个最小的 Messages API 请求，验证 API Key 是否有效、
+模型是否存在、响应格式是否正确，并区分认证失败、模型不存在、
+额度不足和网络错误等情况。
+
+使用 --deep 进一步检查 Claude Code 依赖的能力: SSE 流式输出、工具调用往返、
+系统提示词和长上下文。结果会被缓存，并在 TUI 中以标记显示。
+注意: 长上下文检查会消耗约 3 万输入 token。
+
+使用 --all 并发测试所有已配置的供应商，输出汇总表；任一供应商失败时退出码为 1。
+
 示例:
-  ccm test doubao     测试豆包连接
-  ccm test deepseek   测试 DeepSeek 连接`,
-	Args: cobra.ExactArgs(1),
+  ccm test doubao          测试豆包连接
+  ccm test deepseek        测试 DeepSeek 连接
+  ccm test kimi --deep     检查 Kimi 的各项能力
+  ccm test --all           测试所有已配置的供应商
+  ccm test --all -w 8      使用 8 个并发测试`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if testAll {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if testAll {
+			runTestAll()
+			return
+		}
+
 		name := args[0]
 		green := color.New(color.FgGreen).SprintFunc()
-		yellow := color.New(color.FgYellow).SprintFunc()
 		red := color.New(color.FgRed).SprintFunc()
+		gray := color.New(color.FgHiBlack).SprintFunc()
 
 		// 加载配置
 		cfg, err := config.Load()
@@ -34,6 +67,8 @@ var testCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		ensureUnlocked(cfg)
+
 		// 检查供应商是否存在
 		p, ok := cfg.Providers[name]
 		if !ok {
@@ -42,65 +77,310 @@ var testCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// 获取 API Key (支持环境变量)
-		apiKey := p.APIKey
-		envKey := config.GetEnvAPIKey(name)
-		if envKey != "" {
-			apiKey = envKey
-		}
-
-		if apiKey == "" {
-			fmt.Fprintf(os.Stderr, "%s 供应商 '%s' 未设置 API Key\n", red("错误:"), name)
+		// 获取 API Key (支持环境变量、命令和文件)
+		apiKey, src, err := config.ResolveAPIKey(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
 			os.Exit(1)
 		}
+		pool, _ := src.(*config.KeyPool)
 
 		fmt.Printf("测试供应商: %s (%s)\n", p.DisplayName, p.BaseURL)
-		fmt.Println("正在连接...")
+		fmt.Printf("模型: %s\n", p.Model)
+		if pool != nil {
+			fmt.Printf("密钥: %s\n", pool.Label())
+		}
+		fmt.Println("正在发送测试请求...")
 
-		// 创建 HTTP 请求测试连接
-		client := &http.Client{
-			Timeout: 10 * time.Second,
+		result := probe.Probe(context.Background(), p, apiKey)
+		// 历史记录写入失败不影响测试结果
+		_ = probe.RecordHealth(name, result)
+		if pool != nil {
+			reportKeyResult(name, pool.Label(), result)
 		}
 
-		req, err := http.NewRequest("GET", p.BaseURL, nil)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s 创建请求失败: %v\n", red("错误:"), err)
-			os.Exit(1)
+		if result.OK() {
+			fmt.Printf("%s 连接成功! API Key 有效，模型可用\n", green("✓"))
+			fmt.Printf("  延迟: %v\n", result.Latency)
+			fmt.Printf("  状态码: %d\n", result.Status)
+			if result.Model != "" && result.Model != p.Model {
+				fmt.Printf("  实际模型: %s\n", result.Model)
+			}
+			if deepTest && !runDeepChecks(name, p, apiKey) {
+				os.Exit(1)
+			}
+			return
+		}
+
+		title, hints := describeFailure(result.Class, name)
+		fmt.Printf("%s %s\n", red("✗"), title)
+		if result.Status != 0 {
+			fmt.Printf("  状态码: %d\n", result.Status)
+		}
+		if result.Message != "" {
+			fmt.Printf("  供应商返回: %s\n", result.Message)
+		} else if result.Err != nil {
+			fmt.Printf("  错误: %v\n", result.Err)
+		}
+		fmt.Printf("\n建议:\n")
+		for _, hint := range hints {
+			fmt.Printf("  - %s\n", hint)
+		}
+		fmt.Printf("  %s\n", gray(fmt.Sprintf("API URL: %s", p.BaseURL)))
+		os.Exit(1)
+	},
+}
+
+// reportKeyResult 根据测试结果标记多个密钥中被吊销、耗尽或限流的密钥
+func reportKeyResult(name, label string, result probe.Result) {
+	reason := fmt.Sprintf("HTTP %d", result.Status)
+	// 状态写入失败不影响测试结果
+	switch result.Class {
+	case probe.ClassAuth:
+		// 403 可能只是模型或地区无权限，只有 401 或提示密钥无效时才标记为吊销
+		if result.Status == http.StatusUnauthorized || provider.IsInvalidKeyError(result.Message) {
+			_ = config.MarkKey(name, label, config.KeyRevoked, reason)
 		}
+	case probe.ClassQuota:
+		_ = config.MarkKey(name, label, config.KeyExhausted, reason)
+	case probe.ClassRateLimit:
+		_ = config.RecordRateLimit(name, label)
+	}
+}
 
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+// runTestAll 并发测试所有已配置的供应商并打印汇总表
+func runTestAll() {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
 
-		start := time.Now()
-		resp, err := client.Do(req)
-		latency := time.Since(start)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
 
+	ensureUnlocked(cfg)
+
+	var targets []probe.Target
+	var skipped []string
+	keyFailed := 0
+	labels := map[string]string{} // 配置了多个密钥的供应商本次选中的密钥
+	for _, name := range cfg.SortedNames() {
+		if config.FindKeySource(cfg, name) == nil {
+			skipped = append(skipped, name)
+			continue
+		}
+		apiKey, src, err := config.ResolveAPIKey(name)
 		if err != nil {
-			fmt.Printf("%s 连接失败\n", red("✗"))
-			fmt.Printf("错误: %v\n", err)
-			fmt.Printf("\n可能的原因:\n")
-			fmt.Printf("  - API URL 错误: %s\n", p.BaseURL)
-			fmt.Printf("  - API Key 无效或已过期\n")
-			fmt.Printf("  - 网络连接问题\n")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "%s 获取供应商 '%s' 的 API Key 失败: %v\n", red("错误:"), name, err)
+			keyFailed++
+			continue
+		}
+		if pool, ok := src.(*config.KeyPool); ok {
+			labels[name] = pool.Label()
+		}
+		targets = append(targets, probe.Target{Name: name, Provider: cfg.Providers[name], APIKey: apiKey})
+	}
+	if len(targets) == 0 && keyFailed > 0 {
+		os.Exit(1)
+	}
+	if len(targets) == 0 {
+		fmt.Println("没有已配置的供应商")
+		fmt.Println("请先使用 'ccm add <name> --key \"...\"' 配置供应商")
+		return
+	}
+
+	fmt.Printf("正在测试 %d 个供应商...\n\n", len(targets))
+
+	completed := 0
+	reports := probe.ProbeAll(context.Background(), targets, testWorkers, deepTest, func(r probe.Report) {
+		completed++
+		_ = probe.RecordHealth(r.Name, r.Result)
+		if label, ok := labels[r.Name]; ok {
+			reportKeyResult(r.Name, label, r.Result)
+		}
+		mark := green("✓")
+		if !r.Passed() {
+			mark = red("✗")
 		}
-		defer resp.Body.Close()
+		progress := gray(fmt.Sprintf("[%d/%d]", completed, len(targets)))
+		fmt.Printf("  %s %s %s %s\n", progress, mark, padRight(r.Name, 12), gray(r.Result.Latency.Round(time.Millisecond)))
+	})
 
-		if resp.StatusCode == 200 || resp.StatusCode == 401 || resp.StatusCode == 403 {
-			fmt.Printf("%s 连接成功!\n", green("✓"))
-			fmt.Printf("  延迟: %v\n", latency)
-			fmt.Printf("  状态码: %d\n", resp.StatusCode)
+	// 汇总表
+	fmt.Println()
+	fmt.Println(cyan("汇总:"))
+	header := fmt.Sprintf("  %s %s %s", padRight("供应商", 12), padRight("状态", 8), padRight("延迟", 10))
+	if deepTest {
+		for _, c := range probe.Capabilities {
+			header += " " + c.Badge()
+		}
+	}
+	fmt.Println(header + "  说明")
+
+	failed := 0
+	for _, r := range reports {
+		status, note := green(padRight("正常", 8)), ""
+		switch {
+		case !r.Result.OK():
+			title, _ := describeFailure(r.Result.Class, r.Name)
+			status, note = red(padRight("失败", 8)), red(title)
+		case !r.Passed():
+			status, note = yellow(padRight("部分", 8)), yellow("部分能力不可用")
+		}
+		line := fmt.Sprintf("  %s %s %s", padRight(r.Name, 12), status, padRight(formatMs(r.Result.Latency), 10))
 
-			if resp.StatusCode == 401 || resp.StatusCode == 403 {
-				fmt.Printf("\n%s API Key 可能无效 (状态码 %d)\n", yellow("⚠️"), resp.StatusCode)
-				fmt.Printf("建议: ccm edit %s --key \"新的API密钥\"\n", name)
+		if deepTest {
+			for _, c := range probe.Capabilities {
+				line += " " + capabilityMark(r.Checks, c)
 			}
+			if len(r.Checks) > 0 {
+				report := probe.CapabilityReport{CheckedAt: time.Now(), Model: cfg.Providers[r.Name].Model, Results: r.Checks}
+				if err := probe.SaveCapabilities(r.Name, report); err != nil {
+					fmt.Fprintf(os.Stderr, "%s 缓存检查结果失败: %v\n", red("警告:"), err)
+				}
+			}
+		}
+
+		if !r.Passed() {
+			failed++
+		}
+		fmt.Println(strings.TrimRight(line+"  "+note, " "))
+	}
+
+	if len(skipped) > 0 {
+		fmt.Printf("\n%s 未设置 API Key，已跳过: %s\n", gray("提示:"), strings.Join(skipped, ", "))
+	}
+
+	fmt.Println()
+	// 获取 API Key 失败的供应商同样计为失败
+	failed += keyFailed
+	if failed > 0 {
+		fmt.Printf("%s %d/%d 个供应商测试失败\n", red("✗"), failed, len(reports)+keyFailed)
+		os.Exit(1)
+	}
+	fmt.Printf("%s 全部 %d 个供应商测试通过\n", green("✓"), len(reports))
+}
+
+// capabilityMark 返回能力检查结果的单字符标记
+func capabilityMark(checks []probe.CheckResult, c probe.Capability) string {
+	for _, r := range checks {
+		if r.Capability == c {
+			if r.OK {
+				return color.New(color.FgGreen).Sprint("✓")
+			}
+			return color.New(color.FgRed).Sprint("✗")
+		}
+	}
+	return color.New(color.FgHiBlack).Sprint("-")
+}
+
+// runDeepChecks 执行能力检查并打印能力矩阵，全部通过时返回 true
+func runDeepChecks(name string, p provider.Provider, apiKey string) bool {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	fmt.Println()
+	fmt.Println(cyan("能力检查:"))
+
+	results := probe.Deep(context.Background(), p, apiKey)
+
+	allOK := true
+	for _, r := range results {
+		mark := green("✓")
+		if !r.OK {
+			mark = red("✗")
+			allOK = false
+		}
+		fmt.Printf("  %s %s %s", mark, padRight(capabilityLabel(r.Capability), 12), gray(r.Latency.Round(time.Millisecond)))
+		if r.Detail != "" {
+			fmt.Printf("  %s", red(r.Detail))
+		}
+		fmt.Println()
+	}
+
+	report := probe.CapabilityReport{CheckedAt: time.Now(), Model: p.Model, Results: results}
+	if err := probe.SaveCapabilities(name, report); err != nil {
+		fmt.Printf("\n%s 缓存检查结果失败: %v\n", red("警告:"), err)
+	}
+
+	if !allOK {
+		fmt.Printf("\n%s 部分能力不可用，Claude Code 的相关功能可能无法正常工作\n", red("⚠️"))
+	}
+	return allOK
+}
+
+// capabilityLabel 返回能力的中文名称
+func capabilityLabel(c probe.Capability) string {
+	switch c {
+	case probe.CapStreaming:
+		return "流式输出"
+	case probe.CapToolUse:
+		return "工具调用"
+	case probe.CapSystem:
+		return "系统提示词"
+	case probe.CapLongContext:
+		return "长上下文"
+	default:
+		return string(c)
+	}
+}
+
+// padRight 按终端显示宽度补齐空格（中文字符占两列）
+func padRight(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		s += strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// displayWidth 返回字符串在终端中的显示宽度（中文字符占两列）
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r >= 0x2E80 {
+			w += 2
 		} else {
-			fmt.Printf("%s 连接异常\n", yellow("⚠️"))
-			fmt.Printf("  状态码: %d\n", resp.StatusCode)
+			w++
 		}
-	},
+	}
+	return w
+}
+
+// describeFailure 返回失败类别的中文描述和建议
+func describeFailure(class probe.ErrorClass, name string) (string, []string) {
+	switch class {
+	case probe.ClassNetwork:
+		return "网络连接失败", []string{"检查网络或代理设置", fmt.Sprintf("确认 API URL 是否正确: ccm show %s", name)}
+	case probe.ClassTimeout:
+		return "请求超时", []string{"供应商响应缓慢或网络不稳定，请稍后重试"}
+	case probe.ClassAuth:
+		return "认证失败，API Key 无效或无权限", []string{fmt.Sprintf("更新 API Key: ccm edit %s --key \"新的API密钥\"", name)}
+	case probe.ClassModel:
+		return "模型不存在或无权访问", []string{fmt.Sprintf("更新模型: ccm edit %s --model \"模型名称\"", name)}
+	case probe.ClassQuota:
+		return "账户余额或配额不足", []string{"前往供应商控制台充值或提升配额"}
+	case probe.ClassRateLimit:
+		return "请求过于频繁，已被限流", []string{fmt.Sprintf("稍后重试，或配置故障转移: ccm fallback %s <其他供应商>", name)}
+	case probe.ClassEndpoint:
+		return "接口不存在，API URL 可能错误", []string{fmt.Sprintf("检查 API URL: ccm edit %s --url \"https://...\"", name), "确认协议设置是否正确 (--protocol anthropic|openai)"}
+	case probe.ClassServer:
+		return "供应商服务端错误", []string{"供应商服务异常，请稍后重试"}
+	case probe.ClassResponse:
+		return "响应格式不符合预期", []string{"确认该地址提供 Anthropic Messages API，或使用 --protocol openai"}
+	default:
+		return "请求失败", []string{fmt.Sprintf("检查供应商配置: ccm show %s", name)}
+	}
 }
 
 func init() {
+	testCmd.Flags().BoolVar(&deepTest, "deep", false, "检查流式输出、工具调用、系统提示词和长上下文能力")
+	testCmd.Flags().BoolVarP(&testAll, "all", "a", false, "并发测试所有已配置的供应商")
+	testCmd.Flags().IntVarP(&testWorkers, "workers", "w", probe.DefaultWorkers, "并发测试的数量 (配合 --all)")
 	rootCmd.AddCommand(testCmd)
 }
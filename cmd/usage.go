@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/usage"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageBy    string
+	usageSince string
+	usageJSON  bool
+)
+
+// usageRow 用量统计的 JSON 输出格式
+type usageRow struct {
+	Key        string  `json:"key"`
+	Requests   int     `json:"requests"`
+	Input      int64   `json:"input_tokens"`
+	Output     int64   `json:"output_tokens"`
+	CacheWrite int64   `json:"cache_write_tokens"`
+	CacheRead  int64   `json:"cache_read_tokens"`
+	Cost       float64 `json:"cost"`
+	Unpriced   int     `json:"unpriced_requests,omitempty"`
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage [name...]",
+	Short: "统计各供应商的 token 用量和费用",
+	Long: `统计各供应商的 token 用量和费用
+
+'ccm run' 为每个供应商使用独立的 Claude 配置目录 (~/claude-model/configs/.claude-<name>)，
+本命令解析其中的会话记录，按供应商、项目、日期或模型汇总输入、输出和缓存 token。
+费用优先按供应商配置的单价计算 ('ccm edit <name> --price-input ...')，
+其次按内置的 Claude 模型价格表估算，没有价格信息的请求在费用后标记 *。
+不同货币按汇率换算为统一货币 ('ccm budget --currency CNY')。
+
+--since 支持相对时间 (如 7d、24h) 或日期 (如 2025-01-01)。
+
+示例:
+  ccm usage                       所有供应商的用量
+  ccm usage deepseek              只统计 DeepSeek
+  ccm usage --by day --since 7d   最近 7 天每天的用量
+  ccm usage --by project          按项目统计
+  ccm usage --json                以 JSON 格式输出`,
+	Run: func(cmd *cobra.Command, args []string) {
+		red := color.New(color.FgRed).SprintFunc()
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		by := usage.GroupBy(usageBy)
+		if !validGroupBy(by) {
+			fmt.Fprintf(os.Stderr, "%s 无效的分组方式 '%s'，可选: %s\n", red("错误:"), usageBy, groupKinds())
+			os.Exit(1)
+		}
+
+		since, err := parseSince(usageSince)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = usage.Providers()
+		}
+
+		var entries []usage.Entry
+		for _, name := range names {
+			found, err := usage.Scan(name, since)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 读取 %s 的会话记录失败: %v\n", red("错误:"), name, err)
+				os.Exit(1)
+			}
+			entries = append(entries, found...)
+		}
+
+		pricer := usage.NewPricer(cfg)
+		rows, total := usage.Summarize(entries, by, pricer)
+		if usageJSON {
+			printUsageJSON(rows, total, pricer.Currency())
+			return
+		}
+		if len(entries) == 0 {
+			fmt.Println("没有找到用量记录")
+			fmt.Println("使用 'ccm run <name>' 启动 Claude Code 后，会话记录会按供应商保存")
+			return
+		}
+		printUsageTable(by, rows, total, pricer.Currency())
+	},
+}
+
+// validGroupBy 检查分组方式是否有效
+func validGroupBy(by usage.GroupBy) bool {
+	for _, k := range usage.GroupKinds {
+		if by == k {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKinds 返回所有分组方式，用于错误提示
+func groupKinds() string {
+	kinds := make([]string, len(usage.GroupKinds))
+	for i, k := range usage.GroupKinds {
+		kinds[i] = string(k)
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// parseSince 解析起始时间，支持 7d、24h 等相对时间和 2006-01-02 格式的日期
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			y, m, d := time.Now().AddDate(0, 0, -(n - 1)).Date()
+			return time.Date(y, m, d, 0, 0, 0, 0, time.Local), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的时间 '%s'，示例: 7d、24h、2025-01-01", s)
+}
+
+// printUsageJSON 以 JSON 格式输出用量
+func printUsageJSON(rows []usage.Row, total usage.Totals, currency string) {
+	out := struct {
+		Currency string     `json:"currency"`
+		Rows     []usageRow `json:"rows"`
+		Total    usageRow   `json:"total"`
+	}{Currency: currency, Rows: []usageRow{}, Total: newUsageRow("total", total)}
+	for _, r := range rows {
+		out.Rows = append(out.Rows, newUsageRow(r.Key, r.Totals))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
+
+func newUsageRow(key string, t usage.Totals) usageRow {
+	return usageRow{
+		Key:        key,
+		Requests:   t.Requests,
+		Input:      t.Input,
+		Output:     t.Output,
+		CacheWrite: t.CacheWrite,
+		CacheRead:  t.CacheRead,
+		Cost:       t.Cost,
+		Unpriced:   t.Unpriced,
+	}
+}
+
+// printUsageTable 以表格输出用量
+func printUsageTable(by usage.GroupBy, rows []usage.Row, total usage.Totals, currency string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	headers := map[usage.GroupBy]string{
+		usage.ByProvider: "供应商",
+		usage.ByProject:  "项目",
+		usage.ByDay:      "日期",
+		usage.ByModel:    "模型",
+	}
+
+	width := 12
+	for _, r := range rows {
+		if w := displayWidth(r.Key); w > width {
+			width = w
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s %s %s %s %s %s %s\n",
+		padRight(headers[by], width), padRight("请求", 8), padRight("输入", 10), padRight("输出", 10),
+		padRight("缓存写入", 10), padRight("缓存读取", 10), "费用")
+
+	line := func(t usage.Totals) string {
+		return fmt.Sprintf("%s %s %s %s %s %s",
+			padRight(strconv.Itoa(t.Requests), 8),
+			padRight(formatTokens(t.Input), 10),
+			padRight(formatTokens(t.Output), 10),
+			padRight(formatTokens(t.CacheWrite), 10),
+			padRight(formatTokens(t.CacheRead), 10),
+			yellow(formatCost(t, currency)),
+		)
+	}
+
+	for _, r := range rows {
+		fmt.Printf("  %s %s\n", cyan(padRight(r.Key, width)), line(r.Totals))
+	}
+	fmt.Printf("  %s\n", gray(strings.Repeat("─", width+54)))
+	fmt.Printf("  %s %s\n", padRight("合计", width), line(total))
+
+	if total.Unpriced > 0 {
+		fmt.Printf("\n  %s\n", gray("* 部分请求的模型没有价格信息，未计入费用"))
+	}
+	fmt.Println()
+}
+
+// formatTokens 以 K/M 为单位显示 token 数
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.2fM", float64(n)/1e6)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+// formatCost 显示费用，存在未计价的请求时加 * 标记
+func formatCost(t usage.Totals, currency string) string {
+	if t.Unpriced == t.Requests {
+		return "-"
+	}
+	cost := formatMoney(t.Cost, currency)
+	if t.Unpriced > 0 {
+		cost += "*"
+	}
+	return cost
+}
+
+// formatMoney 以货币符号显示金额
+func formatMoney(amount float64, currency string) string {
+	switch strings.ToUpper(currency) {
+	case "USD":
+		return fmt.Sprintf("$%.2f", amount)
+	case "CNY":
+		return fmt.Sprintf("¥%.2f", amount)
+	default:
+		return fmt.Sprintf("%.2f %s", amount, strings.ToUpper(currency))
+	}
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageBy, "by", string(usage.ByProvider), "分组方式: "+groupKinds())
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "只统计该时间之后的用量 (如 7d、24h、2025-01-01)")
+	usageCmd.Flags().BoolVar(&usageJSON, "json", false, "以 JSON 格式输出")
+	rootCmd.AddCommand(usageCmd)
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var vaultTTL time.Duration
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault [init|unlock|lock|status|passwd|disable]",
+	Short: "管理 API Key 的加密存储",
+	Long: `管理 API Key 的加密存储
+
+启用密钥库后，providers.yaml 中的 API Key 以口令派生的密钥加密保存
+(PBKDF2-SHA256 + AES-256-GCM)。使用密钥的命令会在需要时提示输入口令，
+解锁后的会话在有效期内 (默认 15m，可配置 vault.unlock_ttl) 无需再次输入。
+解锁会话只保存在基于内存的 $XDG_RUNTIME_DIR 中，没有时 (如 macOS) 无法解锁，
+每条命令都会提示输入口令。
+
+非交互环境 (脚本、CI) 可通过环境变量 CCM_PASSPHRASE 提供口令。
+
+示例:
+  ccm vault init             设置口令并加密已保存的 API Key
+  ccm vault unlock --ttl 1h  解锁 1 小时
+  ccm vault lock             立即锁定
+  ccm vault                  查看密钥库状态
+  ccm vault passwd           更换口令
+  ccm vault disable          解密所有 API Key 并停用密钥库`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"init", "unlock", "lock", "status", "passwd", "disable"},
+	Run: func(cmd *cobra.Command, args []string) {
+		green := color.New(color.FgGreen).SprintFunc()
+		red := color.New(color.FgRed).SprintFunc()
+
+		action := "status"
+		if len(args) > 0 {
+			action = args[0]
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 加载配置失败: %v\n", red("错误:"), err)
+			os.Exit(1)
+		}
+		if cfg.Vault == nil && action != "init" && action != "status" {
+			fmt.Fprintf(os.Stderr, "%s 未启用密钥库，请先运行 'ccm vault init'\n", red("错误:"))
+			os.Exit(1)
+		}
+
+		switch action {
+		case "status":
+			printVaultStatus(cfg)
+
+		case "init":
+			if cfg.Vault != nil {
+				fmt.Fprintf(os.Stderr, "%s 密钥库已启用，更换口令请使用 'ccm vault passwd'\n", red("错误:"))
+				os.Exit(1)
+			}
+			passphrase := readNewPassphrase()
+			if err := config.InitVault(passphrase); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 启用密钥库失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已启用密钥库，API Key 已加密保存\n", green("✓"))
+
+		case "unlock":
+			if !config.SessionCached() {
+				fmt.Fprintf(os.Stderr, "%s 本机没有基于内存的 $XDG_RUNTIME_DIR，无法保存解锁会话\n", red("错误:"))
+				fmt.Fprintf(os.Stderr, "使用密钥的命令会在需要时提示输入口令，脚本可通过环境变量 %s 提供口令\n", config.PassphraseEnv)
+				os.Exit(1)
+			}
+			passphrase, err := readPassphrase("密钥库口令: ")
+			if err == nil {
+				err = config.Unlock(passphrase, vaultTTL)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s 解锁失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已解锁，有效期至 %s\n", green("✓"), config.SessionExpiry().Format("15:04"))
+
+		case "lock":
+			if err := config.Lock(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 锁定失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已锁定密钥库\n", green("✓"))
+
+		case "passwd":
+			if !term.IsTerminal(os.Stdin.Fd()) {
+				fmt.Fprintf(os.Stderr, "%s 更换口令需要在终端中进行\n", red("错误:"))
+				os.Exit(1)
+			}
+			ensureUnlocked(cfg)
+			passphrase := readNewPassphrase()
+			if err := config.ChangePassphrase(passphrase); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 更换口令失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已更换口令\n", green("✓"))
+
+		case "disable":
+			ensureUnlocked(cfg)
+			if err := config.DisableVault(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 停用密钥库失败: %v\n", red("错误:"), err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s 已停用密钥库，API Key 恢复为明文保存\n", green("✓"))
+		}
+	},
+}
+
+// printVaultStatus 显示密钥库状态和加密的 API Key 数量
+func printVaultStatus(cfg *config.Config) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	gray := color.New(color.FgHiBlack).SprintFunc()
+
+	if cfg.Vault == nil {
+		fmt.Printf("密钥库: %s\n", gray("未启用 (API Key 以明文保存)"))
+		fmt.Printf("使用 %s 加密保存 API Key\n", "ccm vault init")
+		return
+	}
+
+	encrypted := 0
+	for _, p := range cfg.Providers {
+		if config.IsEncrypted(p.APIKey) {
+			encrypted++
+		}
+		for _, keys := range [][]provider.APIKeyEntry{p.Keys, p.RetiredKeys} {
+			for _, k := range keys {
+				if config.IsEncrypted(k.Key) {
+					encrypted++
+				}
+			}
+		}
+	}
+	fmt.Printf("密钥库:     %s\n", green("已启用"))
+	fmt.Printf("加密的密钥: %d\n", encrypted)
+	fmt.Printf("会话有效期: %s\n", cfg.Vault.TTL())
+	switch expires := config.SessionExpiry(); {
+	case os.Getenv(config.PassphraseEnv) != "":
+		fmt.Printf("状态:       %s\n", green("由 "+config.PassphraseEnv+" 解锁"))
+	case !expires.IsZero():
+		fmt.Printf("状态:       %s\n", green("已解锁，有效期至 "+expires.Format("15:04")))
+	case !config.SessionCached():
+		fmt.Printf("状态:       %s\n", yellow("已锁定 (没有基于内存的 $XDG_RUNTIME_DIR，不保存解锁会话)"))
+	default:
+		fmt.Printf("状态:       %s\n", yellow("已锁定"))
+	}
+}
+
+// ensureUnlocked 密钥库锁定时提示输入口令解锁，无法解锁时退出
+func ensureUnlocked(cfg *config.Config) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	err := config.TryUnlock(cfg)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, config.ErrVaultLocked) {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		fmt.Fprintf(os.Stderr, "非交互环境可通过环境变量 %s 提供口令\n", config.PassphraseEnv)
+		os.Exit(1)
+	}
+
+	passphrase, err := readPassphrase("密钥库口令: ")
+	if err == nil {
+		err = config.Unlock(passphrase, 0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 解锁失败: %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+}
+
+// readPassphrase 从终端读取口令（不回显），非交互环境读取 CCM_PASSPHRASE
+func readPassphrase(prompt string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		if passphrase := os.Getenv(config.PassphraseEnv); passphrase != "" {
+			return passphrase, nil
+		}
+		return "", fmt.Errorf("需要在终端中输入口令，或设置环境变量 %s", config.PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readNewPassphrase 读取并确认新口令，失败时退出
+func readNewPassphrase() string {
+	red := color.New(color.FgRed).SprintFunc()
+
+	passphrase, err := readPassphrase("新口令: ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", red("错误:"), err)
+		os.Exit(1)
+	}
+	if len(passphrase) < 8 {
+		fmt.Fprintf(os.Stderr, "%s 口令至少需要 8 个字符\n", red("错误:"))
+		os.Exit(1)
+	}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		confirm, err := readPassphrase("确认口令: ")
+		if err != nil || confirm != passphrase {
+			fmt.Fprintf(os.Stderr, "%s 两次输入的口令不一致\n", red("错误:"))
+			os.Exit(1)
+		}
+	}
+	return passphrase
+}
+
+func init() {
+	vaultCmd.Flags().DurationVar(&vaultTTL, "ttl", 0, "unlock 的会话有效期 (默认使用配置，15m)")
+	rootCmd.AddCommand(vaultCmd)
+}
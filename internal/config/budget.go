@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency 未配置时费用统计和预算使用的货币
+const DefaultCurrency = "USD"
+
+// DefaultRates 内置汇率: 1 USD 可兑换的各货币数量
+var DefaultRates = map[string]float64{
+	"USD": 1,
+	"CNY": 7.2,
+}
+
+// Budget 单个供应商的费用上限，金额以 Config.Currency 表示，0 表示不限制
+type Budget struct {
+	Daily   float64 `yaml:"daily,omitempty"`
+	Monthly float64 `yaml:"monthly,omitempty"`
+	Enforce bool    `yaml:"enforce,omitempty"` // 超出预算时拒绝启动，否则仅警告
+}
+
+// BudgetCurrency 获取费用统计和预算使用的货币
+func (c *Config) BudgetCurrency() string {
+	if c.Currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(c.Currency)
+}
+
+// normalizeRates 将配置的汇率统一为大写货币代码，拒绝非正数的汇率
+// 在 Load 时调用，之后 Rate 按大写查找
+func (c *Config) normalizeRates() error {
+	if len(c.Rates) == 0 {
+		return nil
+	}
+	rates := make(map[string]float64, len(c.Rates))
+	for currency, r := range c.Rates {
+		code := strings.ToUpper(strings.TrimSpace(currency))
+		if r <= 0 {
+			return fmt.Errorf("%s 的汇率必须大于 0，当前为 %g", currency, r)
+		}
+		if _, dup := rates[code]; dup {
+			return fmt.Errorf("重复配置了 %s 的汇率", code)
+		}
+		rates[code] = r
+	}
+	c.Rates = rates
+	return nil
+}
+
+// Rate 获取 1 USD 可兑换的指定货币数量，配置的汇率优先于内置汇率
+func (c *Config) Rate(currency string) (float64, bool) {
+	currency = strings.ToUpper(currency)
+	if r, ok := c.Rates[currency]; ok {
+		return r, true
+	}
+	r, ok := DefaultRates[currency]
+	return r, ok
+}
+
+// Convert 将金额从一种货币换算为另一种货币
+func (c *Config) Convert(amount float64, from, to string) (float64, error) {
+	if strings.EqualFold(from, to) {
+		return amount, nil
+	}
+	fromRate, ok := c.Rate(from)
+	if !ok {
+		return 0, fmt.Errorf("未配置 %s 的汇率", strings.ToUpper(from))
+	}
+	toRate, ok := c.Rate(to)
+	if !ok {
+		return 0, fmt.Errorf("未配置 %s 的汇率", strings.ToUpper(to))
+	}
+	return amount / fromRate * toRate, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import "testing"
+
+func TestNormalizeRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		rates   map[string]float64
+		want    float64 // CNY 的汇率
+		wantErr bool
+	}{
+		{name: "built-in", want: DefaultRates["CNY"]},
+		{name: "upper case", rates: map[string]float64{"CNY": 7.1}, want: 7.1},
+		{name: "lower case", rates: map[string]float64{"cny": 7.1}, want: 7.1},
+		{name: "zero", rates: map[string]float64{"cny": 0}, wantErr: true},
+		{name: "negative", rates: map[string]float64{"CNY": -1}, wantErr: true},
+		{name: "duplicate", rates: map[string]float64{"cny": 7.1, "CNY": 7.2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Rates: tt.rates}
+			err := cfg.normalizeRates()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("normalizeRates succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeRates: %v", err)
+			}
+			if got, ok := cfg.Rate("cny"); !ok || got != tt.want {
+				t.Errorf("Rate(cny) = %g, %v, want %g", got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"ccm/internal/provider"
@@ -13,7 +15,42 @@ import (
 // Config 用户配置
 type Config struct {
 	Providers map[string]provider.Provider `yaml:"providers"`
-	Default   string                       `yaml:"default,omitempty"` // 默认供应商
+	Default   string                       `yaml:"default,omitempty"`   // 默认供应商
+	Fallback  []string                     `yaml:"fallback,omitempty"`  // 故障转移顺序（经本地网关生效）
+	Currency  string                       `yaml:"currency,omitempty"`  // 费用统计和预算使用的货币，默认 USD
+	Rates     map[string]float64           `yaml:"rates,omitempty"`     // 汇率: 1 USD 可兑换的各货币数量
+	Budgets   map[string]Budget            `yaml:"budgets,omitempty"`   // 各供应商的费用上限
+	Supervise bool                         `yaml:"supervise,omitempty"` // ccm run 默认以监管模式启动
+	Hooks     Hooks                        `yaml:"hooks,omitempty"`     // 监管模式下的会话钩子
+	Vault     *Vault                       `yaml:"vault,omitempty"`     // 密钥库（启用后 API Key 加密保存）
+	Rotation  RotationPolicy               `yaml:"rotation,omitempty"`  // 密钥轮换策略
+}
+
+// Hooks 会话钩子，每项为一条 shell 命令
+type Hooks struct {
+	PostSession []string `yaml:"post_session,omitempty"` // 会话结束后执行
+}
+
+// FailoverChain 获取以 name 开头的故障转移链
+// 当前供应商排在首位，其后按配置顺序排列其余供应商（去重）
+func (c *Config) FailoverChain(name string) []string {
+	chain := []string{name}
+	for _, n := range c.Fallback {
+		if n == name {
+			continue
+		}
+		dup := false
+		for _, existing := range chain {
+			if existing == n {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			chain = append(chain, n)
+		}
+	}
+	return chain
 }
 
 // 配置文件路径
@@ -31,11 +68,23 @@ func GetConfigDir() string {
 	return configDir
 }
 
+// GetClaudeConfigDir 获取供应商独立的 Claude 配置目录 (CLAUDE_CONFIG_DIR)
+// 会话记录等数据按供应商分开保存在该目录下
+func GetClaudeConfigDir(name string) string {
+	return filepath.Join(configDir, ".claude-"+name)
+}
+
 // GetConfigFile 获取配置文件路径
 func GetConfigFile() string {
 	return configFile
 }
 
+// SetConfigDir 更改配置目录，供测试使用临时目录
+func SetConfigDir(dir string) {
+	configDir = dir
+	configFile = filepath.Join(dir, "providers.yaml")
+}
+
 // Load 加载用户配置
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -60,6 +109,9 @@ func Load() (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.normalizeRates(); err != nil {
+		return nil, fmt.Errorf("%s: rates: %w", configFile, err)
+	}
 
 	return cfg, nil
 }
@@ -71,6 +123,15 @@ func Save(cfg *Config) error {
 		return err
 	}
 
+	// 启用密钥库时加密新写入的 API Key
+	if cfg.Vault != nil {
+		sealed, err := sealKeys(cfg)
+		if err != nil {
+			return err
+		}
+		cfg = sealed
+	}
+
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return err
@@ -107,7 +168,7 @@ func AddProvider(p provider.Provider) error {
 	return Save(cfg)
 }
 
-// IsConfigured 检查供应商是否已配置（有 API Key）
+// IsConfigured 检查供应商是否已配置（有 API Key 或其来源）
 func IsConfigured(name string) bool {
 	cfg, err := Load()
 	if err != nil {
@@ -115,7 +176,7 @@ func IsConfigured(name string) bool {
 	}
 
 	p, ok := cfg.Providers[name]
-	return ok && p.APIKey != ""
+	return ok && p.HasAPIKey()
 }
 
 // GetEnvAPIKey 从环境变量获取 API Key
@@ -125,29 +186,6 @@ func GetEnvAPIKey(name string) string {
 	return os.Getenv(envName)
 }
 
-// GetEffectiveAPIKey 获取有效的 API Key
-// 优先使用环境变量，其次使用配置文件
-func GetEffectiveAPIKey(name string) string {
-	// 优先环境变量
-	envKey := GetEnvAPIKey(name)
-	if envKey != "" {
-		return envKey
-	}
-
-	// 从配置文件获取
-	cfg, err := Load()
-	if err != nil {
-		return ""
-	}
-
-	p, ok := cfg.Providers[name]
-	if !ok {
-		return ""
-	}
-
-	return p.APIKey
-}
-
 // SetDefault 设置默认供应商
 func SetDefault(name string) error {
 	cfg, err := Load()
@@ -167,3 +205,33 @@ func GetDefault() string {
 	}
 	return cfg.Default
 }
+
+// SortedNames 按显示顺序返回已配置的供应商名称（预置顺序在前，自定义按名称排序）
+func (c *Config) SortedNames() []string {
+	names := []string{}
+	for _, name := range provider.PresetOrder {
+		if _, ok := c.Providers[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	custom := []string{}
+	for name := range c.Providers {
+		if _, ok := provider.Presets[name]; !ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+// SetFallback 设置故障转移顺序，传入空列表表示清除
+func SetFallback(names []string) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+
+	cfg.Fallback = names
+	return Save(cfg)
+}
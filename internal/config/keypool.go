@@ -0,0 +1,320 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"ccm/internal/provider"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeySourcePool 供应商配置了多个密钥，按策略选择
+const KeySourcePool = "pool"
+
+// KeyStatus 密钥的可用状态
+type KeyStatus string
+
+const (
+	// KeyActive 可用
+	KeyActive KeyStatus = ""
+	// KeyExhausted 余额或配额耗尽，一段时间后重新尝试
+	KeyExhausted KeyStatus = "exhausted"
+	// KeyRevoked 被吊销或无效 (HTTP 401 或提示密钥无效的 403)，需手动恢复
+	KeyRevoked KeyStatus = "revoked"
+)
+
+// keyExhaustedRetry 耗尽的密钥在此时间后重新参与选择
+const keyExhaustedRetry = time.Hour
+
+// KeyState 单个密钥的运行状态，保存在 keys.yaml 中
+type KeyState struct {
+	Status   KeyStatus `yaml:"status,omitempty"`
+	Reason   string    `yaml:"reason,omitempty"`    // 被标记的原因，如 HTTP 401
+	MarkedAt time.Time `yaml:"marked_at,omitempty"` // 被标记的时间
+	LastUsed time.Time `yaml:"last_used,omitempty"` // 最近一次被选中的时间
+	Last429  time.Time `yaml:"last_429,omitempty"`  // 最近一次被限流的时间
+}
+
+// Available 检查密钥当前是否可以被选择
+func (s KeyState) Available(now time.Time) bool {
+	switch s.Status {
+	case KeyRevoked:
+		return false
+	case KeyExhausted:
+		return now.Sub(s.MarkedAt) >= keyExhaustedRetry
+	}
+	return true
+}
+
+// poolState 单个供应商的密钥池状态
+type poolState struct {
+	Next int                 `yaml:"next,omitempty"` // round-robin 下一个密钥的下标
+	Keys map[string]KeyState `yaml:"keys,omitempty"` // 按标签保存的密钥状态
+}
+
+// keyStateMu 保护 keys.yaml 的读写，网关会并发选择密钥
+var keyStateMu sync.Mutex
+
+// keyStateFile 密钥状态文件路径
+func keyStateFile() string {
+	return filepath.Join(configDir, "keys.yaml")
+}
+
+// loadKeyStates 读取所有供应商的密钥状态，文件损坏时视为空
+func loadKeyStates() map[string]poolState {
+	states := map[string]poolState{}
+	data, err := os.ReadFile(keyStateFile())
+	if err != nil {
+		return states
+	}
+	if err := yaml.Unmarshal(data, &states); err != nil {
+		return map[string]poolState{}
+	}
+	return states
+}
+
+// updateKeyState 修改某个供应商的密钥状态并保存
+func updateKeyState(name string, fn func(*poolState)) error {
+	keyStateMu.Lock()
+	defer keyStateMu.Unlock()
+
+	states := loadKeyStates()
+	s := states[name]
+	if s.Keys == nil {
+		s.Keys = map[string]KeyState{}
+	}
+	fn(&s)
+	states[name] = s
+
+	data, err := yaml.Marshal(states)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(keyStateFile(), data, 0600)
+}
+
+// loadPoolState 读取单个供应商的密钥池状态
+func loadPoolState(name string) poolState {
+	keyStateMu.Lock()
+	defer keyStateMu.Unlock()
+
+	s := loadKeyStates()[name]
+	if s.Keys == nil {
+		s.Keys = map[string]KeyState{}
+	}
+	return s
+}
+
+// KeyStates 返回供应商各密钥的运行状态
+func KeyStates(name string) map[string]KeyState {
+	return loadPoolState(name).Keys
+}
+
+// MarkKey 将密钥标记为耗尽或吊销，之后选择时自动跳过
+func MarkKey(name, label string, status KeyStatus, reason string) error {
+	return updateKeyState(name, func(s *poolState) {
+		ks := s.Keys[label]
+		ks.Status = status
+		ks.Reason = reason
+		ks.MarkedAt = time.Now()
+		s.Keys[label] = ks
+	})
+}
+
+// RecordRateLimit 记录密钥被限流 (HTTP 429)，供 least-429 策略使用
+func RecordRateLimit(name, label string) error {
+	return updateKeyState(name, func(s *poolState) {
+		ks := s.Keys[label]
+		ks.Last429 = time.Now()
+		s.Keys[label] = ks
+	})
+}
+
+// ResetKeyState 清除密钥的耗尽、吊销标记和使用记录，label 为空时清除供应商的所有密钥
+func ResetKeyState(name, label string) error {
+	return updateKeyState(name, func(s *poolState) {
+		if label == "" {
+			*s = poolState{Keys: map[string]KeyState{}}
+			return
+		}
+		delete(s.Keys, label)
+	})
+}
+
+// KeyPool 供应商配置的多个密钥
+// 每次 Resolve 按策略选择一个本次尚未尝试过的可用密钥，便于网关在密钥失效时换用下一个
+type KeyPool struct {
+	cfg      *Config
+	name     string
+	keys     []provider.APIKeyEntry
+	strategy provider.KeyStrategy
+	tried    map[string]bool
+	label    string
+}
+
+func (k *KeyPool) Kind() string { return KeySourcePool }
+
+func (k *KeyPool) Detail() string {
+	return fmt.Sprintf("%d 个密钥 (%s)", len(k.keys), k.strategy)
+}
+
+// Label 返回最近一次 Resolve 选中的密钥标签
+func (k *KeyPool) Label() string {
+	return k.label
+}
+
+func (k *KeyPool) Resolve() (string, error) {
+	state := loadPoolState(k.name)
+	now := time.Now()
+
+	var candidates []int
+	unavailable := 0
+	for i, e := range k.keys {
+		if k.tried[e.Label] {
+			continue
+		}
+		if !state.Keys[e.Label].Available(now) {
+			unavailable++
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	if len(candidates) == 0 {
+		if unavailable > 0 {
+			return "", fmt.Errorf("没有可用的密钥 (%d 个已耗尽或被吊销)，可运行 'ccm keys %s --reset' 恢复", unavailable, k.name)
+		}
+		return "", errors.New("没有可用的密钥")
+	}
+
+	var errs []error
+	for _, i := range k.order(candidates, state) {
+		e := k.keys[i]
+		k.tried[e.Label] = true
+		apiKey, err := entrySource(k.cfg, k.name, e).Resolve()
+		if err == nil && apiKey == "" {
+			err = errors.New("API Key 为空")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Label, err))
+			continue
+		}
+
+		k.label = e.Label
+		// 状态写入失败不影响本次使用
+		_ = updateKeyState(k.name, func(s *poolState) {
+			ks := s.Keys[e.Label]
+			ks.LastUsed = now
+			s.Keys[e.Label] = ks
+			s.Next = (i + 1) % len(k.keys)
+		})
+		return apiKey, nil
+	}
+	return "", errors.Join(errs...)
+}
+
+// order 按策略排列候选密钥的下标
+func (k *KeyPool) order(candidates []int, state poolState) []int {
+	switch k.strategy {
+	case provider.StrategyRandom:
+		rand.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	case provider.StrategyLeast429:
+		// 从未被限流的排在最前，其次按最近一次 429 由远到近，相同时优先最久未使用的
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := state.Keys[k.keys[candidates[i]].Label], state.Keys[k.keys[candidates[j]].Label]
+			if !a.Last429.Equal(b.Last429) {
+				return a.Last429.Before(b.Last429)
+			}
+			return a.LastUsed.Before(b.LastUsed)
+		})
+	default:
+		// 从上次使用的下一个开始轮换
+		next, n := state.Next, len(k.keys)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return (candidates[i]-next+n)%n < (candidates[j]-next+n)%n
+		})
+	}
+	return candidates
+}
+
+// poolSource keys: 配置了多个密钥
+func poolSource(cfg *Config, name string, p provider.Provider) KeySource {
+	if len(p.Keys) == 0 {
+		return nil
+	}
+	return newKeyPool(cfg, name, p.Keys, p.EffectiveKeyStrategy())
+}
+
+func newKeyPool(cfg *Config, name string, keys []provider.APIKeyEntry, strategy provider.KeyStrategy) *KeyPool {
+	return &KeyPool{
+		cfg:      cfg,
+		name:     name,
+		keys:     keys,
+		strategy: strategy,
+		tried:    map[string]bool{},
+	}
+}
+
+// entrySource 返回多个密钥中单个密钥的来源
+func entrySource(cfg *Config, name string, e provider.APIKeyEntry) KeySource {
+	switch {
+	case e.KeyCmd != "":
+		return commandKey(e.KeyCmd)
+	case e.KeyFile != "":
+		return fileKey(e.KeyFile)
+	case IsEncrypted(e.Key):
+		return vaultEntrySource{cfg: cfg, name: name, entry: e}
+	}
+	if m := envRefPattern.FindStringSubmatch(e.Key); m != nil {
+		return envKey(m[1])
+	}
+	return literalKey(e.Key)
+}
+
+// vaultEntrySource 密钥库中加密保存的单个密钥
+type vaultEntrySource struct {
+	cfg   *Config
+	name  string
+	entry provider.APIKeyEntry
+}
+
+func (k vaultEntrySource) Kind() string   { return KeySourceVault }
+func (k vaultEntrySource) Detail() string { return "" }
+
+func (k vaultEntrySource) Resolve() (string, error) {
+	return RevealKeyEntry(k.cfg, k.name, k.entry)
+}
+
+// ResolveKeyEntry 获取多个密钥中单个密钥的值
+func ResolveKeyEntry(cfg *Config, name string, e provider.APIKeyEntry) (string, error) {
+	return entrySource(cfg, name, e).Resolve()
+}
+
+// DescribeKeyEntry 返回单个密钥来源的中文说明
+func DescribeKeyEntry(cfg *Config, name string, e provider.APIKeyEntry) string {
+	return DescribeKeySource(entrySource(cfg, name, e))
+}
+
+// NeedsVault 检查获取密钥时是否需要解锁密钥库
+func NeedsVault(src KeySource) bool {
+	if pool, ok := src.(*KeyPool); ok {
+		for _, e := range pool.keys {
+			if IsEncrypted(e.Key) {
+				return true
+			}
+		}
+		return false
+	}
+	return src.Kind() == KeySourceVault
+}
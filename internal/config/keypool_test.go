@@ -0,0 +1,121 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"ccm/internal/provider"
+)
+
+// testPool 在临时配置目录中创建 k1..k3 三个密钥的密钥池
+func testPool(t *testing.T, strategy provider.KeyStrategy) func() *KeyPool {
+	t.Helper()
+	orig := configDir
+	SetConfigDir(t.TempDir())
+	t.Cleanup(func() { SetConfigDir(orig) })
+
+	keys := []provider.APIKeyEntry{
+		{Label: "k1", Key: "sk-1"},
+		{Label: "k2", Key: "sk-2"},
+		{Label: "k3", Key: "sk-3"},
+	}
+	cfg := &Config{Providers: map[string]provider.Provider{"p": {Name: "p", Keys: keys, KeyStrategy: strategy}}}
+	return func() *KeyPool { return newKeyPool(cfg, "p", keys, strategy) }
+}
+
+// pick 模拟多次请求，每次使用新的密钥池选择一个密钥，返回选中的标签
+func pick(t *testing.T, newPool func() *KeyPool, n int) []string {
+	t.Helper()
+	var labels []string
+	for range n {
+		pool := newPool()
+		if _, err := pool.Resolve(); err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		labels = append(labels, pool.Label())
+	}
+	return labels
+}
+
+func TestKeyPoolOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy provider.KeyStrategy
+		setup    func(t *testing.T)
+		want     []string
+	}{
+		{name: "round-robin", strategy: provider.StrategyRoundRobin, want: []string{"k1", "k2", "k3", "k1"}},
+		{name: "default is round-robin", want: []string{"k1", "k2", "k3", "k1"}},
+		{name: "round-robin skips revoked", strategy: provider.StrategyRoundRobin, setup: func(t *testing.T) {
+			if err := MarkKey("p", "k2", KeyRevoked, "HTTP 401"); err != nil {
+				t.Fatal(err)
+			}
+		}, want: []string{"k1", "k3", "k1", "k3"}},
+		{name: "least-429 avoids rate limited", strategy: provider.StrategyLeast429, setup: func(t *testing.T) {
+			if err := RecordRateLimit("p", "k1"); err != nil {
+				t.Fatal(err)
+			}
+		}, want: []string{"k2", "k3", "k2", "k3"}},
+		{name: "least-429 prefers oldest 429", strategy: provider.StrategyLeast429, setup: func(t *testing.T) {
+			for _, label := range []string{"k2", "k3", "k1"} {
+				if err := RecordRateLimit("p", label); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}, want: []string{"k2", "k2", "k2"}},
+		{name: "random skips exhausted", strategy: provider.StrategyRandom, setup: func(t *testing.T) {
+			if err := MarkKey("p", "k1", KeyExhausted, "HTTP 402"); err != nil {
+				t.Fatal(err)
+			}
+			if err := MarkKey("p", "k3", KeyExhausted, "HTTP 402"); err != nil {
+				t.Fatal(err)
+			}
+		}, want: []string{"k2", "k2", "k2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPool := testPool(t, tt.strategy)
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			if got := pick(t, newPool, len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyPoolRandom(t *testing.T) {
+	newPool := testPool(t, provider.StrategyRandom)
+	seen := map[string]int{}
+	for _, label := range pick(t, newPool, 200) {
+		seen[label]++
+	}
+	for _, label := range []string{"k1", "k2", "k3"} {
+		if seen[label] == 0 {
+			t.Errorf("random strategy never picked %s: %v", label, seen)
+		}
+	}
+}
+
+func TestKeyPoolTriesEachKeyOnce(t *testing.T) {
+	for _, strategy := range provider.KeyStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			pool := testPool(t, strategy)()
+			seen := map[string]bool{}
+			for range 3 {
+				key, err := pool.Resolve()
+				if err != nil {
+					t.Fatalf("Resolve: %v", err)
+				}
+				if seen[key] {
+					t.Fatalf("key %s returned twice", key)
+				}
+				seen[key] = true
+			}
+			if _, err := pool.Resolve(); err == nil {
+				t.Error("Resolve succeeded after every key was tried")
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ccm/internal/provider"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// API Key 来源类型
+const (
+	KeySourceEnv     = "env"     // 环境变量（CCM_API_KEY_<NAME> 或 ${ENV} 引用）
+	KeySourceCommand = "command" // api_key_cmd 命令输出
+	KeySourceFile    = "file"    // api_key_file 文件内容
+	KeySourceVault   = "vault"   // 密钥库加密保存
+	KeySourceConfig  = "config"  // providers.yaml 中的明文
+)
+
+// keyCommandTimeout api_key_cmd 的执行超时时间
+const keyCommandTimeout = 30 * time.Second
+
+// keyCommandTTL api_key_cmd 结果在进程内的缓存时间，避免网关每次请求都执行命令
+const keyCommandTTL = 5 * time.Minute
+
+// KeySource API Key 的来源
+type KeySource interface {
+	// Kind 返回来源类型，如 KeySourceCommand
+	Kind() string
+	// Detail 返回来源的说明（变量名、命令或路径），不包含密钥本身
+	Detail() string
+	// Resolve 获取 API Key
+	Resolve() (string, error)
+}
+
+// KeySourceFunc 根据供应商配置返回适用的 API Key 来源，不适用时返回 nil
+type KeySourceFunc func(cfg *Config, name string, p provider.Provider) KeySource
+
+// keySources 按优先级排列的来源，均不适用时使用 api_key 明文
+var keySources = []KeySourceFunc{
+	envOverrideSource,
+	poolSource,
+	commandSource,
+	fileSource,
+	envRefSource,
+	vaultSource,
+}
+
+// RegisterKeySource 注册新的 API Key 来源，优先级低于内置来源、高于 api_key 明文
+func RegisterKeySource(f KeySourceFunc) {
+	keySources = append(keySources, f)
+}
+
+// FindKeySource 返回供应商使用的 API Key 来源，未配置时返回 nil
+func FindKeySource(cfg *Config, name string) KeySource {
+	p := cfg.Providers[name]
+	for _, f := range keySources {
+		if src := f(cfg, name, p); src != nil {
+			return src
+		}
+	}
+	if p.APIKey != "" {
+		return literalKey(p.APIKey)
+	}
+	return nil
+}
+
+// ResolveAPIKey 获取供应商的 API Key 及其来源
+func ResolveAPIKey(name string) (string, KeySource, error) {
+	cfg, err := Load()
+	if err != nil {
+		return "", nil, err
+	}
+	src := FindKeySource(cfg, name)
+	if src == nil {
+		return "", nil, fmt.Errorf("供应商 '%s' 未设置 API Key", name)
+	}
+	apiKey, err := src.Resolve()
+	if err == nil && apiKey == "" {
+		err = errors.New("API Key 为空")
+	}
+	if err != nil {
+		return "", src, fmt.Errorf("%s: %w", DescribeKeySource(src), err)
+	}
+	return apiKey, src, nil
+}
+
+// DescribeKeySource 返回来源的中文说明，如 "命令 pass show doubao"
+func DescribeKeySource(src KeySource) string {
+	label := map[string]string{
+		KeySourceEnv:     "环境变量",
+		KeySourceCommand: "命令",
+		KeySourceFile:    "文件",
+		KeySourceVault:   "密钥库",
+		KeySourceConfig:  "配置文件",
+		KeySourcePool:    "轮换",
+	}[src.Kind()]
+	if label == "" {
+		label = src.Kind()
+	}
+	if detail := src.Detail(); detail != "" {
+		return label + " " + detail
+	}
+	return label
+}
+
+// PlaintextKey 检查 API Key 是否以明文保存在配置文件中
+// 密钥库、命令、文件和 ${ENV} 来源的密钥不应被复制到其他文件，多个密钥需要在启动时选择
+func PlaintextKey(p provider.Provider) bool {
+	return len(p.Keys) == 0 && p.APIKeyCmd == "" && p.APIKeyFile == "" && !IsEncrypted(p.APIKey) &&
+		!envRefPattern.MatchString(strings.TrimSpace(p.APIKey))
+}
+
+// envKey 从环境变量读取
+type envKey string
+
+func (k envKey) Kind() string   { return KeySourceEnv }
+func (k envKey) Detail() string { return string(k) }
+
+func (k envKey) Resolve() (string, error) {
+	value := os.Getenv(string(k))
+	if value == "" {
+		return "", errors.New("未设置")
+	}
+	return value, nil
+}
+
+// envOverrideSource CCM_API_KEY_<NAME> 优先于配置
+func envOverrideSource(_ *Config, name string, _ provider.Provider) KeySource {
+	key := "CCM_API_KEY_" + strings.ToUpper(name)
+	if os.Getenv(key) == "" {
+		return nil
+	}
+	return envKey(key)
+}
+
+// envRefPattern api_key 中的 ${ENV} 引用
+var envRefPattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// envRefSource api_key: ${DOUBAO_KEY}
+func envRefSource(_ *Config, _ string, p provider.Provider) KeySource {
+	m := envRefPattern.FindStringSubmatch(strings.TrimSpace(p.APIKey))
+	if m == nil {
+		return nil
+	}
+	return envKey(m[1])
+}
+
+// commandKey 执行命令并取输出的第一行
+type commandKey string
+
+func (k commandKey) Kind() string   { return KeySourceCommand }
+func (k commandKey) Detail() string { return string(k) }
+
+// commandCache 进程内缓存的命令输出
+// 执行命令时不持有锁，同一命令的并发调用只执行一次并共享结果
+var commandCache = struct {
+	sync.Mutex
+	values   map[string]cachedKey
+	inflight map[string]*commandCall
+}{values: map[string]cachedKey{}, inflight: map[string]*commandCall{}}
+
+type cachedKey struct {
+	value   string
+	expires time.Time
+}
+
+// commandCall 正在执行的命令，完成后关闭 done
+type commandCall struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// keyCommandNoStdin 为 true 时命令不连接标准输入，TUI 运行期间设置以免与其争抢终端输入
+var keyCommandNoStdin atomic.Bool
+
+// SetKeyCommandStdin 设置 api_key_cmd 是否可以从终端读取输入，如密码管理器的主密码
+func SetKeyCommandStdin(enabled bool) {
+	keyCommandNoStdin.Store(!enabled)
+}
+
+func (k commandKey) Resolve() (string, error) {
+	commandCache.Lock()
+	if c, ok := commandCache.values[string(k)]; ok && time.Now().Before(c.expires) {
+		commandCache.Unlock()
+		return c.value, nil
+	}
+	if call, ok := commandCache.inflight[string(k)]; ok {
+		commandCache.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &commandCall{done: make(chan struct{})}
+	commandCache.inflight[string(k)] = call
+	commandCache.Unlock()
+
+	call.value, call.err = k.run()
+
+	commandCache.Lock()
+	delete(commandCache.inflight, string(k))
+	if call.err == nil && call.value != "" {
+		commandCache.values[string(k)] = cachedKey{value: call.value, expires: time.Now().Add(keyCommandTTL)}
+	}
+	commandCache.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+// run 执行命令并取输出的第一行
+func (k commandKey) run() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", string(k))
+	// 密码管理器可能需要在终端中输入主密码，仅在普通终端中连接标准输入和错误输出
+	if !keyCommandNoStdin.Load() && term.IsTerminal(os.Stdin.Fd()) {
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("执行失败: %w (%s)", err, firstLine(exitErr.Stderr))
+		}
+		return "", fmt.Errorf("执行失败: %w", err)
+	}
+	return firstLine(out), nil
+}
+
+// commandSource api_key_cmd: pass show doubao
+func commandSource(_ *Config, _ string, p provider.Provider) KeySource {
+	if p.APIKeyCmd == "" {
+		return nil
+	}
+	return commandKey(p.APIKeyCmd)
+}
+
+// fileKey 读取文件的第一行
+type fileKey string
+
+func (k fileKey) Kind() string   { return KeySourceFile }
+func (k fileKey) Detail() string { return string(k) }
+
+func (k fileKey) Resolve() (string, error) {
+	path := string(k)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, rest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return firstLine(data), nil
+}
+
+// fileSource api_key_file: ~/.secrets/doubao
+func fileSource(_ *Config, _ string, p provider.Provider) KeySource {
+	if p.APIKeyFile == "" {
+		return nil
+	}
+	return fileKey(p.APIKeyFile)
+}
+
+// vaultKeySource 密钥库中加密保存的 API Key
+type vaultKeySource struct {
+	cfg  *Config
+	name string
+}
+
+func (k vaultKeySource) Kind() string   { return KeySourceVault }
+func (k vaultKeySource) Detail() string { return "" }
+
+func (k vaultKeySource) Resolve() (string, error) {
+	return RevealAPIKey(k.cfg, k.name)
+}
+
+// vaultSource api_key: enc:v1:...
+func vaultSource(cfg *Config, name string, p provider.Provider) KeySource {
+	if !IsEncrypted(p.APIKey) {
+		return nil
+	}
+	return vaultKeySource{cfg: cfg, name: name}
+}
+
+// literalKey providers.yaml 中的明文
+type literalKey string
+
+func (k literalKey) Kind() string             { return KeySourceConfig }
+func (k literalKey) Detail() string           { return "" }
+func (k literalKey) Resolve() (string, error) { return string(k), nil }
+
+// firstLine 返回第一行并去除首尾空白（pass 等工具在第一行之后输出附加信息）
+func firstLine(data []byte) string {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return strings.TrimSpace(string(line))
+}
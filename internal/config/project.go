@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ccm/internal/provider"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName 项目配置文件名
+const ProjectFileName = ".ccm.yaml"
+
+// Project 项目配置，指定仓库使用的供应商
+// 该文件通常提交到仓库中，因此不包含 API Key
+type Project struct {
+	Provider string            `yaml:"provider"`        // 供应商名称
+	Model    string            `yaml:"model,omitempty"` // 模型（为空时使用供应商的默认模型）
+	Env      map[string]string `yaml:"env,omitempty"`   // 额外的环境变量
+
+	Path string `yaml:"-"` // 配置文件路径
+}
+
+// 项目配置随仓库分发，不可信：不能修改 API 地址、凭据和请求头，也不能通过
+// 代理、动态库或 Node.js 选项截获请求或注入代码
+var (
+	projectEnvDeniedPrefixes = []string{"ANTHROPIC_", "CLAUDE_CODE_USE_", "LD_", "DYLD_", "NODE_", "NPM_CONFIG_"}
+	projectEnvDenied         = map[string]bool{
+		"CLAUDE_CONFIG_DIR": true,
+		"PATH":              true, "HOME": true, "SHELL": true, "BASH_ENV": true, "ENV": true, "ZDOTDIR": true,
+		"HTTP_PROXY": true, "HTTPS_PROXY": true, "ALL_PROXY": true, "NO_PROXY": true,
+		"SSL_CERT_FILE": true, "SSL_CERT_DIR": true,
+	}
+)
+
+// checkProjectEnv 检查项目配置中的环境变量是否允许设置
+func checkProjectEnv(key string) error {
+	upper := strings.ToUpper(key)
+	denied := projectEnvDenied[upper] || strings.HasPrefix(upper, "CCM_")
+	for _, prefix := range projectEnvDeniedPrefixes {
+		denied = denied || strings.HasPrefix(upper, prefix)
+	}
+	if denied {
+		return fmt.Errorf("项目配置不能设置环境变量 %s (可能泄露 API Key 或注入代码)，请改用 'ccm edit <name> --env'", key)
+	}
+	return nil
+}
+
+// FindProject 从 dir 开始向上查找项目配置文件，未找到时返回 nil
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadProject(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject 读取项目配置文件
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Project
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Provider == "" {
+		return nil, fmt.Errorf("%s: 未指定 provider", path)
+	}
+	for key := range p.Env {
+		if err := checkProjectEnv(key); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	p.Path = path
+	return &p, nil
+}
+
+// Apply 返回应用了项目模型和环境变量的供应商副本
+// 项目的环境变量覆盖供应商配置中的同名变量
+func (p *Project) Apply(prov provider.Provider) provider.Provider {
+	if p.Model != "" {
+		prov.Model = p.Model
+	}
+	if len(p.Env) > 0 {
+		env := make(map[string]string, len(prov.Env)+len(p.Env))
+		for k, v := range prov.Env {
+			env[k] = v
+		}
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		prov.Env = env
+	}
+	return prov
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"math"
+	"time"
+
+	"ccm/internal/provider"
+)
+
+// 密钥轮换策略的默认值
+const (
+	DefaultKeyMaxAgeDays = 90 // 密钥使用超过该天数后提醒更换
+	DefaultKeyWarnDays   = 14 // 密钥过期前提前提醒的天数
+)
+
+// DefaultRetiredGrace 轮换下来的旧密钥默认保留的时长
+const DefaultRetiredGrace = 24 * time.Hour
+
+// RotationPolicy 密钥轮换策略
+type RotationPolicy struct {
+	MaxAgeDays int `yaml:"max_age_days,omitempty"` // 密钥最长使用天数，默认 90，负数表示不检查
+	WarnDays   int `yaml:"warn_days,omitempty"`    // 过期前提前提醒的天数，默认 14
+}
+
+// MaxAge 返回密钥最长使用时长，不检查时返回 0
+func (r RotationPolicy) MaxAge() time.Duration {
+	days := r.MaxAgeDays
+	if days == 0 {
+		days = DefaultKeyMaxAgeDays
+	}
+	if days < 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// WarnBefore 返回过期前开始提醒的时长
+func (r RotationPolicy) WarnBefore() time.Duration {
+	days := r.WarnDays
+	if days <= 0 {
+		days = DefaultKeyWarnDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// KeyWarningKind 密钥提醒的类型
+type KeyWarningKind string
+
+const (
+	// KeyExpired 已过期
+	KeyExpired KeyWarningKind = "expired"
+	// KeyExpiring 即将过期
+	KeyExpiring KeyWarningKind = "expiring"
+	// KeyStale 使用时间超过轮换策略
+	KeyStale KeyWarningKind = "stale"
+)
+
+// KeyWarning 需要更换的密钥
+type KeyWarning struct {
+	Provider string
+	Label    string // 多个密钥时的标签，单个密钥时为空
+	Kind     KeyWarningKind
+	Date     time.Time // 过期时间，KeyStale 时为创建时间
+	Days     int       // 距过期的天数 (不足一天按一天计)，KeyStale 时为已使用的天数
+	Owner    string
+}
+
+// KeyWarnings 返回供应商需要更换的密钥，未记录创建或过期时间的密钥不会提醒
+func (c *Config) KeyWarnings(name string, now time.Time) []KeyWarning {
+	p, ok := c.Providers[name]
+	if !ok || !p.HasAPIKey() {
+		return nil
+	}
+
+	var warnings []KeyWarning
+	check := func(label string, meta provider.KeyMeta) {
+		if w, ok := c.checkKey(meta, now); ok {
+			w.Provider, w.Label, w.Owner = name, label, meta.Owner
+			warnings = append(warnings, w)
+		}
+	}
+	if len(p.Keys) == 0 {
+		check("", p.KeyMeta)
+	}
+	for _, k := range p.Keys {
+		check(k.Label, k.KeyMeta)
+	}
+	return warnings
+}
+
+// AllKeyWarnings 返回所有供应商需要更换的密钥，按供应商名称排序
+func (c *Config) AllKeyWarnings(now time.Time) []KeyWarning {
+	var warnings []KeyWarning
+	for _, name := range c.SortedNames() {
+		warnings = append(warnings, c.KeyWarnings(name, now)...)
+	}
+	return warnings
+}
+
+// checkKey 按过期时间和轮换策略检查单个密钥，已过期优先于即将过期和超期使用
+func (c *Config) checkKey(meta provider.KeyMeta, now time.Time) (KeyWarning, bool) {
+	if !meta.ExpiresAt.IsZero() {
+		left := meta.ExpiresAt.Sub(now)
+		if left <= 0 {
+			return KeyWarning{Kind: KeyExpired, Date: meta.ExpiresAt}, true
+		}
+		if left <= c.Rotation.WarnBefore() {
+			return KeyWarning{Kind: KeyExpiring, Date: meta.ExpiresAt, Days: int(math.Ceil(left.Hours() / 24))}, true
+		}
+	}
+	if maxAge := c.Rotation.MaxAge(); maxAge > 0 && !meta.CreatedAt.IsZero() {
+		if age := now.Sub(meta.CreatedAt); age >= maxAge {
+			return KeyWarning{Kind: KeyStale, Date: meta.CreatedAt, Days: int(age.Hours() / 24)}, true
+		}
+	}
+	return KeyWarning{}, false
+}
+
+// RetiredLabel 轮换下来的旧密钥的标签，label 为空表示 api_key
+// 使用标签中不允许出现的 ':'，不会与多个密钥的标签冲突
+func RetiredLabel(label string) string {
+	if label == "" {
+		label = "api_key"
+	}
+	return label + ":previous"
+}
+
+// RetiredPool 返回供应商尚未过期的旧密钥，没有时返回 nil
+// 网关在当前密钥都失效时使用，便于新密钥出现问题时继续工作
+func RetiredPool(cfg *Config, name string) *KeyPool {
+	now := time.Now()
+	var keys []provider.APIKeyEntry
+	for _, k := range cfg.Providers[name].RetiredKeys {
+		if k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return newKeyPool(cfg, name, keys, provider.StrategyRoundRobin)
+}
+
+// PruneRetiredKeys 删除已过期的旧密钥
+func PruneRetiredKeys(p provider.Provider, now time.Time) provider.Provider {
+	var kept []provider.APIKeyEntry
+	for _, k := range p.RetiredKeys {
+		if k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt) {
+			kept = append(kept, k)
+		}
+	}
+	p.RetiredKeys = kept
+	return p
+}
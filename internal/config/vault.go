@@ -0,0 +1,438 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"ccm/internal/provider"
+)
+
+// 加密存储的 API Key 格式: enc:v1:<base64(nonce|密文)>
+const encPrefix = "enc:v1:"
+
+// vaultIterations PBKDF2 迭代次数
+const vaultIterations = 600000
+
+// DefaultUnlockTTL 解锁会话的默认有效期
+const DefaultUnlockTTL = 15 * time.Minute
+
+// PassphraseEnv 非交互环境下提供密钥库口令的环境变量
+const PassphraseEnv = "CCM_PASSPHRASE"
+
+// vaultCheck 用于验证口令的明文
+const vaultCheck = "ccm-vault"
+
+// Vault 密钥库配置
+// 启用后 providers.yaml 中的 API Key 以口令派生的密钥加密保存 (PBKDF2-SHA256 + AES-256-GCM)
+type Vault struct {
+	Salt       string        `yaml:"salt"`                 // base64 编码的盐
+	Iterations int           `yaml:"iterations"`           // PBKDF2 迭代次数
+	Check      string        `yaml:"check"`                // 加密的校验值，用于验证口令
+	UnlockTTL  time.Duration `yaml:"unlock_ttl,omitempty"` // 解锁会话有效期，默认 15m
+}
+
+var (
+	// ErrVaultLocked 密钥库未解锁
+	ErrVaultLocked = errors.New("密钥库已锁定，请运行 'ccm vault unlock'")
+	// ErrBadPassphrase 口令错误
+	ErrBadPassphrase = errors.New("口令错误")
+)
+
+// vaultKey 本进程已解锁的密钥
+var vaultKey []byte
+
+// IsEncrypted 检查 API Key 是否为加密存储的值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
+
+// TTL 返回解锁会话有效期
+func (v *Vault) TTL() time.Duration {
+	if v.UnlockTTL > 0 {
+		return v.UnlockTTL
+	}
+	return DefaultUnlockTTL
+}
+
+// derive 由口令派生密钥
+func (v *Vault) derive(passphrase string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(v.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("无效的密钥库配置: %w", err)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, v.Iterations, 32)
+}
+
+// verify 检查密钥是否与密钥库匹配
+func (v *Vault) verify(key []byte) bool {
+	plain, err := decrypt(key, v.Check, "check")
+	return err == nil && plain == vaultCheck
+}
+
+// encrypt 加密 value，aad 绑定密文的用途（供应商名称），防止密文被挪用
+func encrypt(key []byte, value, aad string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(aad))
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt 解密 encrypt 生成的值
+func decrypt(key []byte, value, aad string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("密文已损坏")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", errors.New("密文已损坏或密钥不匹配")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// unlockedKey 返回可用的密钥: 本进程已解锁的密钥、CCM_PASSPHRASE 或未过期的解锁会话
+func unlockedKey(v *Vault) ([]byte, error) {
+	if vaultKey != nil && v.verify(vaultKey) {
+		return vaultKey, nil
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		key, err := v.derive(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if !v.verify(key) {
+			return nil, fmt.Errorf("%s: %w", PassphraseEnv, ErrBadPassphrase)
+		}
+		vaultKey = key
+		return key, nil
+	}
+	if key := loadSession(); key != nil && v.verify(key) {
+		vaultKey = key
+		return key, nil
+	}
+	return nil, ErrVaultLocked
+}
+
+// sealKeys 返回 API Key 已加密的供应商副本，供 Save 写入
+func sealKeys(cfg *Config) (*Config, error) {
+	var key []byte
+	seal := func(value, aad string) (string, error) {
+		// ${ENV} 引用不是密钥本身，无需加密
+		if value == "" || IsEncrypted(value) || envRefPattern.MatchString(value) {
+			return value, nil
+		}
+		if key == nil {
+			var err error
+			if key, err = unlockedKey(cfg.Vault); err != nil {
+				return "", err
+			}
+		}
+		return encrypt(key, value, aad)
+	}
+
+	sealed := *cfg
+	sealed.Providers = make(map[string]provider.Provider, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		var err error
+		if p.APIKey, err = seal(p.APIKey, name); err != nil {
+			return nil, err
+		}
+		for _, keys := range []*[]provider.APIKeyEntry{&p.Keys, &p.RetiredKeys} {
+			// 复制切片，避免修改调用方持有的明文
+			*keys = append([]provider.APIKeyEntry(nil), *keys...)
+			for i, k := range *keys {
+				if (*keys)[i].Key, err = seal(k.Key, keyAAD(name, k.Label)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		sealed.Providers[name] = p
+	}
+	return &sealed, nil
+}
+
+// keyAAD 多个密钥中单个密钥的 aad，绑定供应商名称和标签
+func keyAAD(name, label string) string {
+	return name + "/" + label
+}
+
+// RevealAPIKey 返回供应商配置中的 API Key，加密存储时解密
+func RevealAPIKey(cfg *Config, name string) (string, error) {
+	return revealValue(cfg, cfg.Providers[name].APIKey, name)
+}
+
+// RevealKeyEntry 返回多个密钥中指定密钥的值，加密存储时解密
+func RevealKeyEntry(cfg *Config, name string, k provider.APIKeyEntry) (string, error) {
+	return revealValue(cfg, k.Key, keyAAD(name, k.Label))
+}
+
+// revealValue 解密加密存储的值，未加密时原样返回
+func revealValue(cfg *Config, value, aad string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if cfg.Vault == nil {
+		return "", errors.New("API Key 已加密，但未配置密钥库")
+	}
+	key, err := unlockedKey(cfg.Vault)
+	if err != nil {
+		return "", err
+	}
+	return decrypt(key, value, aad)
+}
+
+// TryUnlock 使用本进程的密钥、CCM_PASSPHRASE 或解锁会话解锁密钥库
+// 未启用密钥库时返回 nil，需要输入口令时返回 ErrVaultLocked
+func TryUnlock(cfg *Config) error {
+	if cfg.Vault == nil {
+		return nil
+	}
+	_, err := unlockedKey(cfg.Vault)
+	return err
+}
+
+// InitVault 启用密钥库，加密所有已保存的 API Key 并解锁
+func InitVault(passphrase string) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Vault != nil {
+		return errors.New("密钥库已启用")
+	}
+	return resealVault(cfg, passphrase)
+}
+
+// ChangePassphrase 更换口令，使用新的盐重新加密所有 API Key
+// 明文只在内存中出现，不会写入磁盘
+func ChangePassphrase(passphrase string) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Vault == nil {
+		return errors.New("未启用密钥库")
+	}
+	if err := revealAll(cfg); err != nil {
+		return err
+	}
+	return resealVault(cfg, passphrase)
+}
+
+// resealVault 以新的盐和口令创建密钥库，加密 cfg 中的明文 API Key 后保存并解锁
+func resealVault(cfg *Config, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	v := &Vault{Salt: base64.StdEncoding.EncodeToString(salt), Iterations: vaultIterations}
+	if cfg.Vault != nil {
+		v.UnlockTTL = cfg.Vault.UnlockTTL
+	}
+	key, err := v.derive(passphrase)
+	if err != nil {
+		return err
+	}
+	if v.Check, err = encrypt(key, vaultCheck, "check"); err != nil {
+		return err
+	}
+
+	cfg.Vault = v
+	vaultKey = key
+	if err := Save(cfg); err != nil {
+		vaultKey = nil
+		return err
+	}
+	return saveSession(key, v.TTL())
+}
+
+// revealAll 将 cfg 中加密的 API Key 替换为明文
+func revealAll(cfg *Config) error {
+	for name, p := range cfg.Providers {
+		apiKey, err := RevealAPIKey(cfg, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		p.APIKey = apiKey
+		for _, keys := range [][]provider.APIKeyEntry{p.Keys, p.RetiredKeys} {
+			for i, k := range keys {
+				if keys[i].Key, err = RevealKeyEntry(cfg, name, k); err != nil {
+					return fmt.Errorf("%s/%s: %w", name, k.Label, err)
+				}
+			}
+		}
+		cfg.Providers[name] = p
+	}
+	return nil
+}
+
+// Unlock 验证口令并解锁密钥库，ttl 内的后续命令无需再次输入口令
+// ttl 为 0 时使用配置的有效期
+func Unlock(passphrase string, ttl time.Duration) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Vault == nil {
+		return errors.New("未启用密钥库")
+	}
+	key, err := cfg.Vault.derive(passphrase)
+	if err != nil {
+		return err
+	}
+	if !cfg.Vault.verify(key) {
+		return ErrBadPassphrase
+	}
+	vaultKey = key
+	if ttl <= 0 {
+		ttl = cfg.Vault.TTL()
+	}
+	return saveSession(key, ttl)
+}
+
+// Lock 清除解锁会话
+func Lock() error {
+	vaultKey = nil
+	path := sessionFile()
+	if path == "" {
+		return nil
+	}
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// DisableVault 解密所有 API Key 并停用密钥库
+func DisableVault() error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	if cfg.Vault == nil {
+		return errors.New("未启用密钥库")
+	}
+	if err := revealAll(cfg); err != nil {
+		return err
+	}
+	cfg.Vault = nil
+	if err := Save(cfg); err != nil {
+		return err
+	}
+	return Lock()
+}
+
+// session 解锁会话文件内容
+type session struct {
+	Key     []byte    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// sessionFile 解锁会话文件路径，没有安全的位置时返回空字符串
+// 会话中保存的是解密用的密钥，只能放在基于内存且仅所有者可访问的运行时目录中，
+// 不能与 providers.yaml 中的密文放在同一磁盘上
+func sessionFile() string {
+	dirs := []string{os.Getenv("XDG_RUNTIME_DIR")}
+	if runtime.GOOS == "linux" {
+		dirs = append(dirs, fmt.Sprintf("/run/user/%d", os.Getuid()))
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && info.Mode().Perm()&0077 == 0 {
+			return filepath.Join(dir, "ccm-vault.session")
+		}
+	}
+	return ""
+}
+
+// SessionCached 检查本机是否可以保存解锁会话，不能保存时每次都需要输入口令
+func SessionCached() bool {
+	return sessionFile() != ""
+}
+
+// SessionExpiry 返回解锁会话的过期时间，没有有效会话时返回零值
+func SessionExpiry() time.Time {
+	s, err := readSession()
+	if err != nil || time.Now().After(s.Expires) {
+		return time.Time{}
+	}
+	return s.Expires
+}
+
+func saveSession(key []byte, ttl time.Duration) error {
+	path := sessionFile()
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(session{Key: key, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	// 使用 0600 权限，仅所有者可读写
+	return os.WriteFile(path, data, 0600)
+}
+
+func readSession() (*session, error) {
+	path := sessionFile()
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// loadSession 读取未过期的解锁会话密钥，过期的会话文件会被删除
+func loadSession() []byte {
+	s, err := readSession()
+	if err != nil {
+		return nil
+	}
+	if time.Now().After(s.Expires) {
+		_ = os.Remove(sessionFile())
+		return nil
+	}
+	return s.Key
+}
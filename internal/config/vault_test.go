@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"ccm/internal/provider"
+)
+
+// testVault 创建使用较少迭代次数的密钥库，返回其派生密钥
+func testVault(t *testing.T, passphrase string) (*Vault, []byte) {
+	t.Helper()
+	v := &Vault{Salt: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")), Iterations: 1000}
+	key, err := v.derive(passphrase)
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	if v.Check, err = encrypt(key, vaultCheck, "check"); err != nil {
+		t.Fatalf("encrypt check: %v", err)
+	}
+	return v, key
+}
+
+// tamper 翻转密文中的一个字节
+func tamper(t *testing.T, value string) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	data[len(data)-1] ^= 0x01
+	return encPrefix + base64.StdEncoding.EncodeToString(data)
+}
+
+func TestSealOpen(t *testing.T) {
+	const passphrase = "correct horse"
+	v, key := testVault(t, passphrase)
+
+	tests := []struct {
+		name       string
+		sealAAD    string
+		openAAD    string
+		passphrase string
+		tamper     bool
+		wantErr    bool
+	}{
+		{name: "provider key", sealAAD: "deepseek", openAAD: "deepseek", passphrase: passphrase},
+		{name: "pool key", sealAAD: keyAAD("openai", "team-a"), openAAD: "openai/team-a", passphrase: passphrase},
+		{name: "wrong passphrase", sealAAD: "deepseek", openAAD: "deepseek", passphrase: "wrong horse", wantErr: true},
+		{name: "tampered ciphertext", sealAAD: "deepseek", openAAD: "deepseek", passphrase: passphrase, tamper: true, wantErr: true},
+		{name: "moved to another provider", sealAAD: "deepseek", openAAD: "qwen", passphrase: passphrase, wantErr: true},
+		{name: "moved to another label", sealAAD: keyAAD("openai", "team-a"), openAAD: keyAAD("openai", "team-b"), passphrase: passphrase, wantErr: true},
+		{name: "pool key used as provider key", sealAAD: keyAAD("openai", "team-a"), openAAD: "openai", passphrase: passphrase, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := encrypt(key, "sk-secret", tt.sealAAD)
+			if err != nil {
+				t.Fatalf("encrypt: %v", err)
+			}
+			if !IsEncrypted(sealed) || strings.Contains(sealed, "sk-secret") {
+				t.Fatalf("sealed value %q is not an enc:v1 ciphertext", sealed)
+			}
+			if tt.tamper {
+				sealed = tamper(t, sealed)
+			}
+
+			openKey, err := v.derive(tt.passphrase)
+			if err != nil {
+				t.Fatalf("derive: %v", err)
+			}
+			if got := v.verify(openKey); got != (tt.passphrase == passphrase) {
+				t.Errorf("verify = %v", got)
+			}
+			plain, err := decrypt(openKey, sealed, tt.openAAD)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decrypt = %q, want error", plain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if plain != "sk-secret" {
+				t.Errorf("decrypt = %q, want %q", plain, "sk-secret")
+			}
+		})
+	}
+}
+
+func TestSealKeysReveal(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	v, key := testVault(t, "correct horse")
+	vaultKey = key
+	t.Cleanup(func() { vaultKey = nil })
+
+	cfg := &Config{
+		Vault: v,
+		Providers: map[string]provider.Provider{
+			"deepseek": {Name: "deepseek", APIKey: "sk-deepseek"},
+			"env":      {Name: "env", APIKey: "${DEEPSEEK_KEY}"},
+			"openai": {Name: "openai", Keys: []provider.APIKeyEntry{
+				{Label: "team-a", Key: "sk-a"},
+				{Label: "team-b", Key: "sk-b"},
+			}},
+		},
+	}
+	sealed, err := sealKeys(cfg)
+	if err != nil {
+		t.Fatalf("sealKeys: %v", err)
+	}
+	if cfg.Providers["deepseek"].APIKey != "sk-deepseek" || cfg.Providers["openai"].Keys[0].Key != "sk-a" {
+		t.Fatal("sealKeys modified the caller's plaintext")
+	}
+	if got := sealed.Providers["env"].APIKey; got != "${DEEPSEEK_KEY}" {
+		t.Errorf("env reference sealed as %q", got)
+	}
+
+	if got, err := RevealAPIKey(sealed, "deepseek"); err != nil || got != "sk-deepseek" {
+		t.Errorf("RevealAPIKey = %q, %v", got, err)
+	}
+	for i, want := range []string{"sk-a", "sk-b"} {
+		e := sealed.Providers["openai"].Keys[i]
+		if !IsEncrypted(e.Key) {
+			t.Fatalf("key %s not sealed", e.Label)
+		}
+		if got, err := RevealKeyEntry(sealed, "openai", e); err != nil || got != want {
+			t.Errorf("RevealKeyEntry(%s) = %q, %v", e.Label, got, err)
+		}
+	}
+
+	// 密文被复制到其他供应商或标签时无法解密
+	moved := sealed.Providers["qwen"]
+	moved.APIKey = sealed.Providers["deepseek"].APIKey
+	sealed.Providers["qwen"] = moved
+	if _, err := RevealAPIKey(sealed, "qwen"); err == nil {
+		t.Error("RevealAPIKey accepted a ciphertext moved to another provider")
+	}
+	swapped := sealed.Providers["openai"].Keys[0]
+	swapped.Label = "team-b"
+	if _, err := RevealKeyEntry(sealed, "openai", swapped); err == nil {
+		t.Error("RevealKeyEntry accepted a ciphertext moved to another label")
+	}
+
+	// 锁定后无法解密
+	vaultKey = nil
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	if _, err := RevealAPIKey(sealed, "deepseek"); err != ErrVaultLocked {
+		t.Errorf("RevealAPIKey while locked = %v, want ErrVaultLocked", err)
+	}
+}
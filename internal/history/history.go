@@ -0,0 +1,206 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"ccm/internal/config"
+)
+
+// 启动来源
+const (
+	SourceRun    = "run"
+	SourceSwitch = "switch"
+	SourceTUI    = "tui"
+	SourceList   = "list"
+	SourceRerun  = "rerun"
+)
+
+// keep 压缩历史文件时保留的启动记录数
+const keep = 2000
+
+// compactSize 历史文件超过该大小时压缩
+const compactSize = 1 << 20
+
+// Entry 一次 Claude Code 启动记录
+// 监管模式下会话结束后追加一条同 ID 的记录，补充结束时间和退出码
+type Entry struct {
+	ID         string     `json:"id"`
+	Time       time.Time  `json:"time"`
+	Provider   string     `json:"provider,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	SmallModel string     `json:"small_model,omitempty"` // --small-model 覆盖的小模型
+	Dir        string     `json:"dir,omitempty"`
+	Args       []string   `json:"args,omitempty"`
+	EnvKeys    []string   `json:"env_keys,omitempty"` // --env 设置的环境变量名，值可能是密钥，不保存
+	Timeout    string     `json:"timeout,omitempty"`  // --timeout 非默认值时记录，如 10m0s
+	Source     string     `json:"source,omitempty"`
+	Proxy      string     `json:"proxy,omitempty"`
+	Ended      *time.Time `json:"ended,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+}
+
+// Finished 返回是否已记录退出状态
+func (e Entry) Finished() bool {
+	return e.ExitCode != nil
+}
+
+// mu 串行化同一进程内的写入，不同 ccm 进程之间通过 lock 串行化
+var mu sync.Mutex
+
+// historyFile 启动历史文件路径
+func historyFile() string {
+	return filepath.Join(config.GetConfigDir(), "history.jsonl")
+}
+
+// Record 记录一次启动，返回记录 ID（用于 Finish）
+func Record(e Entry) (string, error) {
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return e.ID, appendEntry(e)
+}
+
+// Finish 记录启动的结束时间和退出码
+func Finish(id string, ended time.Time, exitCode int) error {
+	return appendEntry(Entry{ID: id, Time: ended, Ended: &ended, ExitCode: &exitCode})
+}
+
+func appendEntry(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		return err
+	}
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(historyFile(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(historyFile()); err == nil && info.Size() > compactSize {
+		return compact()
+	}
+	return nil
+}
+
+// Load 读取所有启动记录（已合并结束状态），按启动时间先后排列
+func Load() ([]Entry, error) {
+	f, err := os.Open(historyFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	index := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		// 跳过写入中断留下的残缺行
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.ID == "" {
+			continue
+		}
+		if i, ok := index[e.ID]; ok {
+			if e.ExitCode != nil {
+				entries[i].Ended = e.Ended
+				entries[i].ExitCode = e.ExitCode
+			}
+			continue
+		}
+		if e.Provider == "" {
+			continue
+		}
+		index[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, scanner.Err()
+}
+
+// lock 锁定启动历史，防止其他 ccm 进程在压缩期间追加记录，返回解锁函数
+func lock() (func(), error) {
+	f, err := os.OpenFile(historyFile()+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// compact 只保留最近的 keep 条启动记录，需持有 lock
+func compact() error {
+	entries, err := Load()
+	if err != nil {
+		return err
+	}
+	if len(entries) > keep {
+		entries = entries[len(entries)-keep:]
+	}
+
+	tmp := historyFile() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, historyFile())
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
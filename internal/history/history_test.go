@@ -0,0 +1,110 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ccm/internal/config"
+)
+
+// useTempDir 将配置目录切换到临时目录
+func useTempDir(t *testing.T) {
+	t.Helper()
+	orig := config.GetConfigDir()
+	config.SetConfigDir(t.TempDir())
+	t.Cleanup(func() { config.SetConfigDir(orig) })
+}
+
+func TestRecordFinish(t *testing.T) {
+	useTempDir(t)
+
+	id, err := Record(Entry{Provider: "deepseek", Model: "deepseek-chat", EnvKeys: []string{"SECRET_TOKEN"}, Source: SourceRun})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if _, err := Record(Entry{Provider: "kimi", Source: SourceRun}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := Finish(id, time.Now(), 3); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load returned %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.ID != id || !e.Finished() || *e.ExitCode != 3 || e.Ended == nil {
+		t.Errorf("finished entry = %+v", e)
+	}
+	if e := entries[1]; e.Provider != "kimi" || e.Finished() {
+		t.Errorf("running entry = %+v", e)
+	}
+	if got := entries[0].EnvKeys; len(got) != 1 || got[0] != "SECRET_TOKEN" {
+		t.Errorf("EnvKeys = %q", got)
+	}
+}
+
+func TestCompactKeepsConcurrentAppends(t *testing.T) {
+	useTempDir(t)
+
+	// 预先写入超过 compactSize 的旧记录，下一次追加时触发压缩
+	f, err := os.Create(historyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	old := time.Now().Add(-time.Hour)
+	dir := strings.Repeat("d", 400)
+	for i := 0; i < keep+1000; i++ {
+		e := Entry{ID: fmt.Sprintf("old-%d", i), Time: old.Add(time.Duration(i) * time.Millisecond), Provider: "old", Dir: dir}
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := Record(Entry{ID: fmt.Sprintf("new-%d", i), Provider: "new"}); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Record: %v", err)
+	}
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) > keep+n {
+		t.Errorf("history not compacted: %d entries", len(entries))
+	}
+	added := 0
+	for _, e := range entries {
+		if e.Provider == "new" {
+			added++
+		}
+	}
+	if added != n {
+		t.Errorf("kept %d of %d concurrent appends", added, n)
+	}
+}
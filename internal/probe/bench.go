@@ -0,0 +1,213 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
+)
+
+// BenchOptions 基准测试参数
+type BenchOptions struct {
+	Requests    int    // 每个供应商的请求总数
+	Concurrency int    // 并发数
+	MaxTokens   int    // 每个请求的最大输出 token
+	Prompt      string // 请求内容
+}
+
+// DefaultBenchPrompt 默认的基准测试请求内容
+const DefaultBenchPrompt = "Write a short paragraph (about 100 words) describing how a hash map works."
+
+// Sample 单个流式请求的测量结果
+type Sample struct {
+	TTFT         time.Duration // 首个 token 到达耗时
+	Total        time.Duration // 完整请求耗时
+	OutputTokens int
+	Err          error
+}
+
+// BenchResult 单个供应商的基准测试汇总
+type BenchResult struct {
+	Provider     string
+	Model        string
+	Requests     int
+	Errors       int
+	TTFT         time.Duration // 首字延迟中位数
+	TokensPerSec float64       // 平均生成速度 (首字之后)
+	P50          time.Duration // 完整请求耗时 p50
+	P95          time.Duration // 完整请求耗时 p95
+	LastError    error
+}
+
+// ErrorRate 返回失败请求占比
+func (r BenchResult) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Bench 并发发送流式请求并汇总延迟和吞吐
+func Bench(ctx context.Context, p provider.Provider, apiKey string, opts BenchOptions) BenchResult {
+	if opts.Requests < 1 {
+		opts.Requests = 1
+	}
+	if opts.Concurrency < 1 || opts.Concurrency > opts.Requests {
+		opts.Concurrency = opts.Requests
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = DefaultBenchPrompt
+	}
+
+	samples := make([]Sample, opts.Requests)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				samples[i] = streamSample(ctx, &p, apiKey, opts)
+			}
+		}()
+	}
+	for i := 0; i < opts.Requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return summarize(p, samples)
+}
+
+// streamSample 发送一个流式请求并测量首字延迟和输出 token 数
+func streamSample(ctx context.Context, p *provider.Provider, apiKey string, opts BenchOptions) Sample {
+	body, err := json.Marshal(map[string]any{
+		"model":      p.Model,
+		"max_tokens": opts.MaxTokens,
+		"stream":     true,
+		"messages":   []map[string]string{{"role": "user", "content": opts.Prompt}},
+	})
+	if err != nil {
+		return Sample{Err: err}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 4*DefaultTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := proxy.Send(ctx, p, apiKey, body)
+	if err != nil {
+		return Sample{Total: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return Sample{Total: time.Since(start), Err: fmt.Errorf("HTTP %d: %s", resp.StatusCode, errorMessage(data))}
+	}
+
+	var s Sample
+	deltas := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event struct {
+			Type  string `json:"type"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+		}
+		if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event) != nil {
+			continue
+		}
+		switch event.Type {
+		case "content_block_delta":
+			if s.TTFT == 0 {
+				s.TTFT = time.Since(start)
+			}
+			deltas++
+		case "message_delta":
+			s.OutputTokens = event.Usage.OutputTokens
+		}
+	}
+	s.Total = time.Since(start)
+
+	if err := scanner.Err(); err != nil {
+		s.Err = err
+		return s
+	}
+	if deltas == 0 {
+		s.Err = errors.New("流中没有内容")
+		return s
+	}
+	// 部分供应商不返回用量，按增量事件数估算
+	if s.OutputTokens == 0 {
+		s.OutputTokens = deltas
+	}
+	return s
+}
+
+// summarize 汇总样本
+func summarize(p provider.Provider, samples []Sample) BenchResult {
+	result := BenchResult{Provider: p.Name, Model: p.Model, Requests: len(samples)}
+
+	var ttfts, totals []time.Duration
+	var rates []float64
+	for _, s := range samples {
+		if s.Err != nil {
+			result.Errors++
+			result.LastError = s.Err
+			continue
+		}
+		ttfts = append(ttfts, s.TTFT)
+		totals = append(totals, s.Total)
+		if gen := s.Total - s.TTFT; gen > 0 {
+			rates = append(rates, float64(s.OutputTokens)/gen.Seconds())
+		}
+	}
+
+	result.TTFT = percentile(ttfts, 0.5)
+	result.P50 = percentile(totals, 0.5)
+	result.P95 = percentile(totals, 0.95)
+	if len(rates) > 0 {
+		sum := 0.0
+		for _, r := range rates {
+			sum += r
+		}
+		result.TokensPerSec = sum / float64(len(rates))
+	}
+	return result
+}
+
+// percentile 使用最近秩法计算百分位
+func percentile(values []time.Duration, q float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
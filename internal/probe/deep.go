@@ -0,0 +1,363 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Capability Claude Code 依赖的供应商能力
+type Capability string
+
+const (
+	CapStreaming   Capability = "streaming"    // SSE 流式输出
+	CapToolUse     Capability = "tool_use"     // 工具调用往返
+	CapSystem      Capability = "system"       // 系统提示词
+	CapLongContext Capability = "long_context" // 长上下文
+)
+
+// Capabilities 能力检查的执行和显示顺序
+var Capabilities = []Capability{CapStreaming, CapToolUse, CapSystem, CapLongContext}
+
+// LongContextTokens 长上下文检查使用的大致 token 数（接近 Claude Code 实际请求规模）
+const LongContextTokens = 32000
+
+// Badge 返回能力的单字母标记（用于 TUI）
+func (c Capability) Badge() string {
+	switch c {
+	case CapStreaming:
+		return "S"
+	case CapToolUse:
+		return "T"
+	case CapSystem:
+		return "P"
+	case CapLongContext:
+		return "L"
+	default:
+		return "?"
+	}
+}
+
+// CheckResult 单项能力检查结果
+type CheckResult struct {
+	Capability Capability    `yaml:"capability"`
+	OK         bool          `yaml:"ok"`
+	Latency    time.Duration `yaml:"latency"`
+	Detail     string        `yaml:"detail,omitempty"` // 失败原因
+}
+
+// Deep 依次检查供应商的各项能力
+// 请求以 Anthropic 格式发送，OpenAI 协议的供应商会经过与网关相同的协议转换
+func Deep(ctx context.Context, p provider.Provider, apiKey string) []CheckResult {
+	checks := map[Capability]func(context.Context, *provider.Provider, string) error{
+		CapStreaming:   checkStreaming,
+		CapToolUse:     checkToolUse,
+		CapSystem:      checkSystem,
+		CapLongContext: checkLongContext,
+	}
+
+	results := make([]CheckResult, 0, len(Capabilities))
+	for _, c := range Capabilities {
+		start := time.Now()
+		err := checks[c](ctx, &p, apiKey)
+		r := CheckResult{Capability: c, OK: err == nil, Latency: time.Since(start)}
+		if err != nil {
+			r.Detail = err.Error()
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// messageResponse Messages API 非流式响应
+type messageResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+func (m *messageResponse) text() string {
+	var b strings.Builder
+	for _, c := range m.Content {
+		if c.Type == "text" {
+			b.WriteString(c.Text)
+		}
+	}
+	return b.String()
+}
+
+// send 发送非流式请求并解析响应
+func send(ctx context.Context, p *provider.Provider, apiKey string, payload map[string]any) (*messageResponse, error) {
+	payload["model"] = p.Model
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*DefaultTimeout)
+	defer cancel()
+
+	resp, err := proxy.Send(ctx, p, apiKey, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, errorMessage(data))
+	}
+
+	var msg messageResponse
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("响应不是合法的 JSON: %w", err)
+	}
+	return &msg, nil
+}
+
+// checkStreaming 检查 SSE 事件序列是否完整
+func checkStreaming(ctx context.Context, p *provider.Provider, apiKey string) error {
+	body, err := json.Marshal(map[string]any{
+		"model":      p.Model,
+		"max_tokens": 32,
+		"stream":     true,
+		"messages":   []map[string]string{{"role": "user", "content": "Count from 1 to 5."}},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*DefaultTimeout)
+	defer cancel()
+
+	resp, err := proxy.Send(ctx, p, apiKey, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, errorMessage(data))
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "text/event-stream") {
+		return fmt.Errorf("Content-Type 不是 text/event-stream: %s", ct)
+	}
+
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event) == nil {
+			seen[event.Type] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, want := range []string{"message_start", "content_block_delta", "message_stop"} {
+		if !seen[want] {
+			return fmt.Errorf("流中缺少 %s 事件", want)
+		}
+	}
+	return nil
+}
+
+// weatherTool 工具调用检查使用的工具定义
+var weatherTool = map[string]any{
+	"name":        "get_weather",
+	"description": "Get the current weather for a city.",
+	"input_schema": map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]string{"type": "string"}},
+		"required":   []string{"city"},
+	},
+}
+
+// checkToolUse 检查工具调用和 tool_result 回传的完整往返
+func checkToolUse(ctx context.Context, p *provider.Provider, apiKey string) error {
+	question := map[string]any{"role": "user", "content": "What is the weather in Paris? Use the get_weather tool."}
+
+	first, err := send(ctx, p, apiKey, map[string]any{
+		"max_tokens": 256,
+		"tools":      []any{weatherTool},
+		"messages":   []any{question},
+	})
+	if err != nil {
+		return err
+	}
+
+	var toolUse map[string]any
+	for _, c := range first.Content {
+		if c.Type == "tool_use" {
+			var input map[string]any
+			if err := json.Unmarshal(c.Input, &input); err != nil {
+				return fmt.Errorf("工具参数不是合法的 JSON: %w", err)
+			}
+			if _, ok := input["city"]; !ok {
+				return errors.New("工具参数缺少 city 字段")
+			}
+			toolUse = map[string]any{"type": "tool_use", "id": c.ID, "name": c.Name, "input": input}
+			break
+		}
+	}
+	if toolUse == nil {
+		return fmt.Errorf("模型未调用工具 (stop_reason: %s)", first.StopReason)
+	}
+
+	second, err := send(ctx, p, apiKey, map[string]any{
+		"max_tokens": 256,
+		"tools":      []any{weatherTool},
+		"messages": []any{
+			question,
+			map[string]any{"role": "assistant", "content": []any{toolUse}},
+			map[string]any{"role": "user", "content": []any{map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": toolUse["id"],
+				"content":     "Sunny, 22°C",
+			}}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("回传 tool_result 失败: %w", err)
+	}
+	if strings.TrimSpace(second.text()) == "" {
+		return errors.New("回传 tool_result 后模型没有返回文本")
+	}
+	return nil
+}
+
+// checkSystem 检查系统提示词是否生效
+func checkSystem(ctx context.Context, p *provider.Provider, apiKey string) error {
+	msg, err := send(ctx, p, apiKey, map[string]any{
+		"max_tokens": 16,
+		"system":     "You are a test fixture. Whatever the user says, reply with exactly the single word PONG.",
+		"messages":   []map[string]string{{"role": "user", "content": "Hello, who are you?"}},
+	})
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToUpper(msg.text()), "PONG") {
+		return fmt.Errorf("系统提示词未生效，回复: %q", truncate(msg.text(), 40))
+	}
+	return nil
+}
+
+// checkLongContext 检查长上下文请求，并验证模型能读到开头的内容
+func checkLongContext(ctx context.Context, p *provider.Provider, apiKey string) error {
+	const filler = "The quick brown fox jumps over the lazy dog near the quiet river bank. "
+	var b strings.Builder
+	b.WriteString("Remember this secret word: AZALEA.\n\n")
+	for b.Len() < LongContextTokens*4 {
+		b.WriteString(filler)
+	}
+	b.WriteString("\n\nWhat was the secret word? Reply with the word only.")
+
+	msg, err := send(ctx, p, apiKey, map[string]any{
+		"max_tokens": 16,
+		"messages":   []map[string]string{{"role": "user", "content": b.String()}},
+	})
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToUpper(msg.text()), "AZALEA") {
+		return fmt.Errorf("未能从长上下文中找回内容，回复: %q", truncate(msg.text(), 40))
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return string(r)
+}
+
+// CapabilityReport 缓存的能力检查结果
+type CapabilityReport struct {
+	CheckedAt time.Time     `yaml:"checked_at"`
+	Model     string        `yaml:"model"`
+	Results   []CheckResult `yaml:"results"`
+}
+
+// Passed 返回某项能力是否通过，未检查时 ok 为 false
+func (r CapabilityReport) Passed(c Capability) (passed, ok bool) {
+	for _, res := range r.Results {
+		if res.Capability == c {
+			return res.OK, true
+		}
+	}
+	return false, false
+}
+
+// capabilitiesFile 能力缓存文件路径
+func capabilitiesFile() string {
+	return filepath.Join(config.GetConfigDir(), "capabilities.yaml")
+}
+
+// LoadCapabilities 读取缓存的能力检查结果
+func LoadCapabilities() (map[string]CapabilityReport, error) {
+	reports := map[string]CapabilityReport{}
+
+	data, err := os.ReadFile(capabilitiesFile())
+	if os.IsNotExist(err) {
+		return reports, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// SaveCapabilities 缓存某个供应商的能力检查结果
+func SaveCapabilities(name string, report CapabilityReport) error {
+	reports, err := LoadCapabilities()
+	if err != nil {
+		reports = map[string]CapabilityReport{}
+	}
+	reports[name] = report
+
+	data, err := yaml.Marshal(reports)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(capabilitiesFile(), data, 0600)
+}
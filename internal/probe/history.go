@@ -0,0 +1,169 @@
+package probe
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"ccm/internal/config"
+)
+
+// HealthWindow 计算可用率的时间窗口
+const HealthWindow = 7 * 24 * time.Hour
+
+// healthKeep 压缩历史文件时每个供应商保留的记录数
+const healthKeep = 500
+
+// healthCompactSize 历史文件超过该大小时压缩
+const healthCompactSize = 1 << 20
+
+// HealthRecord 单次连接测试的历史记录
+type HealthRecord struct {
+	Time      time.Time  `json:"time"`
+	Provider  string     `json:"provider"`
+	Status    int        `json:"status,omitempty"`
+	LatencyMS int64      `json:"latency_ms"`
+	Class     ErrorClass `json:"class,omitempty"`
+}
+
+// OK 返回该次测试是否成功
+func (r HealthRecord) OK() bool {
+	return r.Class == ClassNone
+}
+
+// Latency 返回请求耗时
+func (r HealthRecord) Latency() time.Duration {
+	return time.Duration(r.LatencyMS) * time.Millisecond
+}
+
+// healthMu 串行化同一进程内的并发写入
+var healthMu sync.Mutex
+
+// healthFile 健康历史文件路径
+func healthFile() string {
+	return filepath.Join(config.GetConfigDir(), "health.jsonl")
+}
+
+// RecordHealth 追加一条连接测试结果
+func RecordHealth(name string, r Result) error {
+	rec := HealthRecord{
+		Time:      time.Now(),
+		Provider:  name,
+		Status:    r.Status,
+		LatencyMS: r.Latency.Milliseconds(),
+		Class:     r.Class,
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(healthFile(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(healthFile()); err == nil && info.Size() > healthCompactSize {
+		return compactHealth()
+	}
+	return nil
+}
+
+// LoadHealth 读取所有供应商的健康历史，按时间先后排列
+func LoadHealth() (map[string][]HealthRecord, error) {
+	history := map[string][]HealthRecord{}
+
+	f, err := os.Open(healthFile())
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec HealthRecord
+		// 跳过写入中断留下的残缺行
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil || rec.Provider == "" {
+			continue
+		}
+		history[rec.Provider] = append(history[rec.Provider], rec)
+	}
+	return history, scanner.Err()
+}
+
+// compactHealth 每个供应商只保留最近的 healthKeep 条记录
+func compactHealth() error {
+	history, err := LoadHealth()
+	if err != nil {
+		return err
+	}
+
+	var kept []HealthRecord
+	for _, records := range history {
+		if len(records) > healthKeep {
+			records = records[len(records)-healthKeep:]
+		}
+		kept = append(kept, records...)
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Time.Before(kept[j].Time) })
+
+	tmp := healthFile() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range kept {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, healthFile())
+}
+
+// Uptime 返回 since 之后的测试成功率和测试次数
+func Uptime(records []HealthRecord, since time.Time) (float64, int) {
+	total, ok := 0, 0
+	for _, r := range records {
+		if r.Time.Before(since) {
+			continue
+		}
+		total++
+		if r.OK() {
+			ok++
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return float64(ok) / float64(total) * 100, total
+}
@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
+
+	"gopkg.in/yaml.v3"
+)
+
+// modelsPageLimit Anthropic /v1/models 每页数量
+const modelsPageLimit = 1000
+
+// modelList 模型列表响应，Anthropic 和 OpenAI 格式均使用 data[].id
+type modelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// ListModels 查询供应商的模型列表接口，返回排序后的模型 ID
+// Anthropic 协议使用 /v1/models（自动翻页），OpenAI 协议使用 /models
+func ListModels(ctx context.Context, p provider.Provider, apiKey string) ([]string, error) {
+	base := strings.TrimRight(p.BaseURL, "/")
+	openai := p.EffectiveProtocol() == provider.ProtocolOpenAI
+
+	seen := map[string]bool{}
+	var models []string
+	afterID := ""
+	for {
+		endpoint := base + "/models"
+		if !openai {
+			q := url.Values{"limit": {fmt.Sprint(modelsPageLimit)}}
+			if afterID != "" {
+				q.Set("after_id", afterID)
+			}
+			endpoint = base + "/v1/models?" + q.Encode()
+		}
+
+		page, err := fetchModels(ctx, p, endpoint, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			if m.ID != "" && !seen[m.ID] {
+				seen[m.ID] = true
+				models = append(models, m.ID)
+			}
+		}
+
+		if openai || !page.HasMore || page.LastID == "" || page.LastID == afterID {
+			break
+		}
+		afterID = page.LastID
+	}
+
+	sort.Strings(models)
+	return models, nil
+}
+
+// fetchModels 请求一页模型列表
+func fetchModels(ctx context.Context, p provider.Provider, endpoint, apiKey string) (*modelList, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.Authorize(req, apiKey)
+	p.ApplyHeaders(req.Header)
+	if p.EffectiveProtocol() != provider.ProtocolOpenAI {
+		req.Header.Set("anthropic-version", proxy.AnthropicVersion)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, errorMessage(data))
+	}
+
+	var page modelList
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("响应不是合法的 JSON: %w", err)
+	}
+	return &page, nil
+}
+
+// ModelCache 缓存的模型列表
+type ModelCache struct {
+	FetchedAt time.Time `yaml:"fetched_at"`
+	Models    []string  `yaml:"models"`
+}
+
+// modelsFile 模型列表缓存文件路径
+func modelsFile() string {
+	return filepath.Join(config.GetConfigDir(), "models.yaml")
+}
+
+// LoadModelCache 读取缓存的模型列表
+func LoadModelCache() (map[string]ModelCache, error) {
+	caches := map[string]ModelCache{}
+
+	data, err := os.ReadFile(modelsFile())
+	if os.IsNotExist(err) {
+		return caches, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &caches); err != nil {
+		return nil, err
+	}
+	return caches, nil
+}
+
+// SaveModelCache 缓存某个供应商的模型列表
+func SaveModelCache(name string, models []string) error {
+	caches, err := LoadModelCache()
+	if err != nil {
+		caches = map[string]ModelCache{}
+	}
+	caches[name] = ModelCache{FetchedAt: time.Now(), Models: models}
+
+	data, err := yaml.Marshal(caches)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(modelsFile(), data, 0600)
+}
@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"context"
+	"sync"
+
+	"ccm/internal/provider"
+)
+
+// DefaultWorkers 并发探测的默认工作协程数
+const DefaultWorkers = 4
+
+// Target 待探测的供应商
+type Target struct {
+	Name     string
+	Provider provider.Provider
+	APIKey   string
+}
+
+// Report 单个供应商的探测报告
+type Report struct {
+	Name   string
+	Result Result
+	Checks []CheckResult // 仅在 deep 模式且基础探测成功时填充
+}
+
+// ProbeAll 使用有限数量的工作协程并发探测多个供应商
+// onDone 在调用方协程中按完成顺序依次调用，可用于显示进度；返回值与 targets 顺序一致
+func ProbeAll(ctx context.Context, targets []Target, workers int, deep bool, onDone func(Report)) []Report {
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+
+	reports := make([]Report, len(targets))
+	jobs := make(chan int)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				t := targets[i]
+				r := Report{Name: t.Name, Result: Probe(ctx, t.Provider, t.APIKey)}
+				if deep && r.Result.OK() {
+					r.Checks = Deep(ctx, t.Provider, t.APIKey)
+				}
+				reports[i] = r
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range targets {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	for i := range done {
+		if onDone != nil {
+			onDone(reports[i])
+		}
+	}
+	return reports
+}
+
+// Passed 返回报告中的基础探测和能力检查是否全部通过
+func (r Report) Passed() bool {
+	if !r.Result.OK() {
+		return false
+	}
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
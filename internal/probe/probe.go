@@ -0,0 +1,268 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccm/internal/provider"
+	"ccm/internal/proxy"
+)
+
+// DefaultTimeout 单次探测的超时时间
+const DefaultTimeout = 30 * time.Second
+
+// ErrorClass 探测失败的类别
+type ErrorClass string
+
+const (
+	ClassNone      ErrorClass = ""
+	ClassNetwork   ErrorClass = "network"    // 网络不可达、DNS、TLS 等
+	ClassTimeout   ErrorClass = "timeout"    // 超时
+	ClassAuth      ErrorClass = "auth"       // API Key 无效或无权限
+	ClassModel     ErrorClass = "model"      // 模型不存在
+	ClassQuota     ErrorClass = "quota"      // 余额/配额不足
+	ClassRateLimit ErrorClass = "rate_limit" // 请求过于频繁
+	ClassEndpoint  ErrorClass = "endpoint"   // 接口路径不存在 (BaseURL 错误)
+	ClassServer    ErrorClass = "server"     // 供应商服务端错误
+	ClassResponse  ErrorClass = "response"   // 响应格式不符合预期
+	ClassRequest   ErrorClass = "request"    // 其他请求错误
+)
+
+// String 返回简短的英文描述（用于 TUI）
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassNone:
+		return "ok"
+	case ClassNetwork:
+		return "network error"
+	case ClassTimeout:
+		return "timeout"
+	case ClassAuth:
+		return "auth failed"
+	case ClassModel:
+		return "unknown model"
+	case ClassQuota:
+		return "quota exhausted"
+	case ClassRateLimit:
+		return "rate limited"
+	case ClassEndpoint:
+		return "endpoint not found"
+	case ClassServer:
+		return "server error"
+	case ClassResponse:
+		return "bad response"
+	default:
+		return "request error"
+	}
+}
+
+// Result 探测结果
+type Result struct {
+	Status  int           // HTTP 状态码，网络错误时为 0
+	Latency time.Duration // 完整请求耗时
+	Class   ErrorClass    // 失败类别，成功时为 ClassNone
+	Message string        // 供应商返回的错误描述
+	Model   string        // 响应中返回的模型
+	Err     error         // 网络或解析错误
+}
+
+// OK 返回探测是否成功
+func (r Result) OK() bool {
+	return r.Class == ClassNone
+}
+
+// AsError 返回可读的失败原因，成功时为 nil
+func (r Result) AsError() error {
+	if r.OK() {
+		return nil
+	}
+	switch {
+	case r.Message != "":
+		return fmt.Errorf("%s: %s", r.Class, r.Message)
+	case r.Err != nil:
+		return fmt.Errorf("%s: %v", r.Class, r.Err)
+	case r.Status != 0:
+		return fmt.Errorf("%s (HTTP %d)", r.Class, r.Status)
+	default:
+		return errors.New(r.Class.String())
+	}
+}
+
+// Probe 向供应商发送一个最小的真实请求，验证 Key、模型和响应格式
+func Probe(ctx context.Context, p provider.Provider, apiKey string) Result {
+	req, err := newRequest(ctx, p, apiKey)
+	if err != nil {
+		return Result{Class: ClassRequest, Err: err}
+	}
+
+	client := &http.Client{Timeout: DefaultTimeout}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Latency: time.Since(start), Class: classifyNetError(err), Err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Status: resp.StatusCode, Latency: latency, Class: classifyNetError(err), Err: err}
+	}
+
+	result := Result{Status: resp.StatusCode, Latency: latency}
+	if resp.StatusCode >= 400 {
+		result.Message = errorMessage(data)
+		result.Class = classifyStatus(resp.StatusCode, result.Message)
+		return result
+	}
+
+	model, err := checkShape(p.EffectiveProtocol(), data)
+	if err != nil {
+		result.Class = ClassResponse
+		result.Err = err
+		return result
+	}
+	result.Model = model
+	return result
+}
+
+// newRequest 构造最小化的探测请求
+func newRequest(ctx context.Context, p provider.Provider, apiKey string) (*http.Request, error) {
+	base := strings.TrimRight(p.BaseURL, "/")
+	message := []map[string]string{{"role": "user", "content": "ping"}}
+
+	url := base + "/v1/messages"
+	if p.EffectiveProtocol() == provider.ProtocolOpenAI {
+		url = base + "/chat/completions"
+	}
+
+	body, err := json.Marshal(map[string]any{"model": p.Model, "max_tokens": 8, "messages": message})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.Authorize(req, apiKey)
+	p.ApplyHeaders(req.Header)
+	if p.EffectiveProtocol() != provider.ProtocolOpenAI {
+		req.Header.Set("anthropic-version", proxy.AnthropicVersion)
+	}
+	return req, nil
+}
+
+// checkShape 验证成功响应的结构，返回响应中的模型名
+func checkShape(proto provider.Protocol, data []byte) (string, error) {
+	if proto == provider.ProtocolOpenAI {
+		var resp struct {
+			Model   string            `json:"model"`
+			Choices []json.RawMessage `json:"choices"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return "", fmt.Errorf("响应不是合法的 JSON: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return "", errors.New("响应缺少 choices 字段")
+		}
+		return resp.Model, nil
+	}
+
+	var resp struct {
+		Type    string            `json:"type"`
+		Model   string            `json:"model"`
+		Content []json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("响应不是合法的 JSON: %w", err)
+	}
+	if resp.Type != "message" || resp.Content == nil {
+		return "", errors.New("响应不是 Anthropic Messages 格式")
+	}
+	return resp.Model, nil
+}
+
+// errorMessage 提取 Anthropic 或 OpenAI 格式的错误描述
+func errorMessage(data []byte) string {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+		Msg   string          `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+		var text string
+		if json.Unmarshal(body.Error, &text) == nil && text != "" {
+			return text
+		}
+		if body.Msg != "" {
+			return body.Msg
+		}
+	}
+
+	text := strings.TrimSpace(string(data))
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return text
+}
+
+// classifyStatus 根据状态码和错误描述判断失败类别
+func classifyStatus(status int, message string) ErrorClass {
+	lower := strings.ToLower(message)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		if provider.IsQuotaError(message) {
+			return ClassQuota
+		}
+		return ClassAuth
+	case status == http.StatusPaymentRequired:
+		return ClassQuota
+	case status == http.StatusTooManyRequests:
+		if provider.IsQuotaError(message) {
+			return ClassQuota
+		}
+		return ClassRateLimit
+	case status == http.StatusNotFound:
+		if strings.Contains(lower, "model") {
+			return ClassModel
+		}
+		return ClassEndpoint
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		if strings.Contains(lower, "model") {
+			return ClassModel
+		}
+		if provider.IsQuotaError(message) {
+			return ClassQuota
+		}
+		return ClassRequest
+	case status >= 500:
+		return ClassServer
+	default:
+		return ClassRequest
+	}
+}
+
+// classifyNetError 区分超时和其他网络错误
+func classifyNetError(err error) ErrorClass {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ClassTimeout
+	}
+	return ClassNetwork
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AuthMode 供应商的认证方式
+type AuthMode string
+
+const (
+	// AuthBearer Authorization: Bearer <key>，对应 ANTHROPIC_AUTH_TOKEN（默认）
+	AuthBearer AuthMode = "bearer"
+	// AuthAPIKey x-api-key: <key>，对应 ANTHROPIC_API_KEY（Anthropic 原生接口）
+	AuthAPIKey AuthMode = "x-api-key"
+	// AuthHeader 自定义请求头 <AuthParam>: <key>
+	AuthHeader AuthMode = "header"
+	// AuthQuery URL 查询参数 <AuthParam>=<key>，Claude Code 无法直接使用，需经本地网关
+	AuthQuery AuthMode = "query"
+)
+
+// ParseAuth 解析认证方式: bearer、x-api-key、header:<名称> 或 query:<参数名>
+func ParseAuth(s string) (AuthMode, string, error) {
+	mode, param, _ := strings.Cut(s, ":")
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	param = strings.TrimSpace(param)
+
+	switch AuthMode(mode) {
+	case AuthBearer, AuthAPIKey:
+		if param != "" {
+			return "", "", fmt.Errorf("认证方式 %s 不需要参数", mode)
+		}
+		return AuthMode(mode), "", nil
+	case AuthHeader, AuthQuery:
+		if param == "" {
+			return "", "", fmt.Errorf("认证方式 %s 需要指定名称，如 %s:X-Token", mode, mode)
+		}
+		if AuthMode(mode) == AuthHeader {
+			param = http.CanonicalHeaderKey(param)
+		}
+		return AuthMode(mode), param, nil
+	}
+	return "", "", fmt.Errorf("不支持的认证方式: %s (可选: bearer, x-api-key, header:<名称>, query:<参数名>)", s)
+}
+
+// EffectiveAuth 返回认证方式和参数，未配置时沿用预置值，默认 bearer
+func (p Provider) EffectiveAuth() (AuthMode, string) {
+	if p.Auth != "" {
+		return p.Auth, p.AuthParam
+	}
+	if preset, ok := Presets[p.Name]; ok && preset.Auth != "" {
+		return preset.Auth, preset.AuthParam
+	}
+	return AuthBearer, ""
+}
+
+// AuthLabel 返回认证方式的显示文本
+func (p Provider) AuthLabel() string {
+	mode, param := p.EffectiveAuth()
+	if param != "" {
+		return string(mode) + ":" + param
+	}
+	return string(mode)
+}
+
+// Authorize 按认证方式将 API Key 写入请求
+func (p Provider) Authorize(req *http.Request, apiKey string) {
+	mode, param := p.EffectiveAuth()
+	switch mode {
+	case AuthAPIKey:
+		req.Header.Set("x-api-key", apiKey)
+	case AuthHeader:
+		req.Header.Set(param, apiKey)
+	case AuthQuery:
+		q := req.URL.Query()
+		q.Set(param, apiKey)
+		req.URL.RawQuery = q.Encode()
+	default:
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// AuthEnv 返回 Claude Code 直连供应商时的凭据环境变量
+// header 方式的密钥通过 ClientHeaders 发送，同时设置 ANTHROPIC_AUTH_TOKEN 以免 Claude Code 要求登录
+func (p Provider) AuthEnv(apiKey string) EnvVar {
+	if mode, _ := p.EffectiveAuth(); mode == AuthAPIKey {
+		return EnvVar{"ANTHROPIC_API_KEY", apiKey}
+	}
+	return EnvVar{"ANTHROPIC_AUTH_TOKEN", apiKey}
+}
+
+// ClientHeaders 返回 Claude Code 直连供应商时需要附加的请求头（ANTHROPIC_CUSTOM_HEADERS 格式）
+// 包括附加请求头和 header 方式的认证头
+func (p Provider) ClientHeaders(apiKey string) string {
+	headers := p.CustomHeaders()
+	if mode, param := p.EffectiveAuth(); mode == AuthHeader {
+		auth := param + ": " + apiKey
+		if headers == "" {
+			return auth
+		}
+		return auth + "\n" + headers
+	}
+	return headers
+}
+
+// NeedsProxy 判断 Claude Code 是否必须经本地网关连接供应商
+// OpenAI 协议需要转换请求，query 认证无法通过环境变量配置
+func (p Provider) NeedsProxy() bool {
+	mode, _ := p.EffectiveAuth()
+	return p.EffectiveProtocol() == ProtocolOpenAI || mode == AuthQuery
+}
@@ -0,0 +1,33 @@
+package provider
+
+import "strings"
+
+// quotaHints 表示余额或配额不足的关键字
+var quotaHints = []string{"quota", "insufficient", "balance", "billing", "credit", "余额", "欠费", "额度"}
+
+// invalidKeyHints 表示 API Key 无效或已被吊销的关键字
+var invalidKeyHints = []string{
+	"invalid api key", "invalid_api_key", "invalid x-api-key", "incorrect api key",
+	"api key not valid", "api key is invalid", "invalid token", "revoked",
+	"密钥无效", "无效的密钥", "令牌无效", "api key 无效",
+}
+
+// IsQuotaError 检查供应商返回的错误描述是否表示余额或配额不足
+func IsQuotaError(message string) bool {
+	return containsAny(strings.ToLower(message), quotaHints)
+}
+
+// IsInvalidKeyError 检查供应商返回的错误描述是否表示 API Key 无效或已被吊销
+// 403 也可能是模型或地区无权限，只有匹配这些关键字时才视为密钥失效
+func IsInvalidKeyError(message string) bool {
+	return containsAny(strings.ToLower(message), invalidKeyHints)
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
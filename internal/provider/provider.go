@@ -1,5 +1,12 @@
 package provider
 
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
 // ProviderType 供应商类型
 type ProviderType string
 
@@ -10,18 +17,350 @@ const (
 	TypeProxy ProviderType = "proxy"
 )
 
+// Protocol 供应商 API 协议
+type Protocol string
+
+const (
+	// ProtocolAnthropic Anthropic Messages API (/v1/messages)
+	ProtocolAnthropic Protocol = "anthropic"
+	// ProtocolOpenAI OpenAI Chat Completions API (/chat/completions)，需经本地网关转换
+	ProtocolOpenAI Protocol = "openai"
+)
+
 // Provider 供应商配置
 type Provider struct {
-	Name        string       `yaml:"name"`         // 供应商名称（用于命令行）
-	DisplayName string       `yaml:"display_name"` // 显示名称（中文）
-	APIKey      string       `yaml:"api_key"`      // API 密钥
-	BaseURL     string       `yaml:"base_url"`     // API 基础 URL
-	Model       string       `yaml:"model"`        // 默认模型
-	KeyURL      string       `yaml:"key_url"`      // 获取 API Key 的网址
-	Type        ProviderType `yaml:"type"`         // 供应商类型
+	Name        string             `yaml:"name"`                   // 供应商名称（用于命令行）
+	DisplayName string             `yaml:"display_name"`           // 显示名称（中文）
+	APIKey      string             `yaml:"api_key"`                // API 密钥（支持 ${ENV} 引用环境变量）
+	APIKeyCmd   string             `yaml:"api_key_cmd,omitempty"`  // 输出 API 密钥的命令（如 pass show doubao）
+	APIKeyFile  string             `yaml:"api_key_file,omitempty"` // 保存 API 密钥的文件
+	KeyMeta     KeyMeta            `yaml:"key_meta,omitempty"`     // api_key 的创建时间、过期时间和负责人
+	Keys        []APIKeyEntry      `yaml:"keys,omitempty"`         // 多个带标签的 API 密钥（设置后优先于 api_key）
+	KeyStrategy KeyStrategy        `yaml:"key_strategy,omitempty"` // 多个密钥的选择策略，默认 round-robin
+	RetiredKeys []APIKeyEntry      `yaml:"retired_keys,omitempty"` // 轮换下来的旧密钥，过期前在网关中作为备用
+	BaseURL     string             `yaml:"base_url"`               // API 基础 URL
+	Model       string             `yaml:"model"`                  // 默认模型（主循环使用）
+	Models      []string           `yaml:"models,omitempty"`       // 可用模型列表
+	Roles       *ModelRoles        `yaml:"roles,omitempty"`        // Claude 模型角色到供应商模型的映射，未设置时沿用预置映射
+	KeyURL      string             `yaml:"key_url"`                // 获取 API Key 的网址
+	Type        ProviderType       `yaml:"type"`                   // 供应商类型
+	Protocol    Protocol           `yaml:"protocol,omitempty"`     // API 协议（为空时沿用预置值，默认 anthropic）
+	Auth        AuthMode           `yaml:"auth,omitempty"`         // 认证方式（为空时沿用预置值，默认 bearer）
+	AuthParam   string             `yaml:"auth_param,omitempty"`   // header/query 认证使用的请求头或参数名
+	Pricing     *Pricing           `yaml:"pricing,omitempty"`      // 默认单价，未按模型配置时使用（为空时沿用预置值）
+	ModelPrices map[string]Pricing `yaml:"model_prices,omitempty"` // 按模型配置的单价，模型名前缀匹配
+	Env         map[string]string  `yaml:"env,omitempty"`          // 启动 Claude Code 时额外设置的环境变量
+	Headers     map[string]string  `yaml:"headers,omitempty"`      // 请求供应商时附加的 HTTP 头
+}
+
+// HasAPIKey 是否配置了 API Key 或其来源（命令、文件、多个密钥）
+func (p Provider) HasAPIKey() bool {
+	return p.APIKey != "" || p.APIKeyCmd != "" || p.APIKeyFile != "" || len(p.Keys) > 0
+}
+
+// APIKeyEntry 带标签的 API 密钥，多个厂商账号分摊限流时使用
+type APIKeyEntry struct {
+	Label   string `yaml:"label"`              // 标签（如账号名），同一供应商内唯一
+	Key     string `yaml:"key,omitempty"`      // API 密钥（支持 ${ENV} 引用环境变量）
+	KeyCmd  string `yaml:"key_cmd,omitempty"`  // 输出 API 密钥的命令
+	KeyFile string `yaml:"key_file,omitempty"` // 保存 API 密钥的文件
+	KeyMeta `yaml:",inline"`
+}
+
+// KeyMeta API 密钥的元数据，用于轮换提醒
+type KeyMeta struct {
+	CreatedAt time.Time `yaml:"created_at,omitempty"` // 创建或最近一次更换的时间
+	ExpiresAt time.Time `yaml:"expires_at,omitempty"` // 过期时间（如 2026-12-31）
+	Owner     string    `yaml:"owner,omitempty"`      // 负责人
+}
+
+// IsZero 判断是否未设置任何元数据（供 yaml omitempty 使用）
+func (m KeyMeta) IsZero() bool {
+	return m.CreatedAt.IsZero() && m.ExpiresAt.IsZero() && m.Owner == ""
+}
+
+// KeyStrategy 多个密钥的选择策略
+type KeyStrategy string
+
+const (
+	// StrategyRoundRobin 依次轮换
+	StrategyRoundRobin KeyStrategy = "round-robin"
+	// StrategyRandom 随机选择
+	StrategyRandom KeyStrategy = "random"
+	// StrategyLeast429 优先选择最久未被限流 (HTTP 429) 的密钥
+	StrategyLeast429 KeyStrategy = "least-429"
+)
+
+// KeyStrategies 支持的密钥选择策略
+var KeyStrategies = []KeyStrategy{StrategyRoundRobin, StrategyRandom, StrategyLeast429}
+
+// EffectiveKeyStrategy 获取实际使用的密钥选择策略
+func (p Provider) EffectiveKeyStrategy() KeyStrategy {
+	if p.KeyStrategy == "" {
+		return StrategyRoundRobin
+	}
+	return p.KeyStrategy
+}
+
+// FindKey 按标签查找密钥，返回下标，不存在时返回 -1
+func (p Provider) FindKey(label string) int {
+	for i, k := range p.Keys {
+		if k.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// ModelRoles Claude Code 各模型角色对应的供应商模型，为空表示沿用 Model
+type ModelRoles struct {
+	Opus      string `yaml:"opus,omitempty"`       // ANTHROPIC_DEFAULT_OPUS_MODEL
+	Sonnet    string `yaml:"sonnet,omitempty"`     // ANTHROPIC_DEFAULT_SONNET_MODEL
+	Haiku     string `yaml:"haiku,omitempty"`      // ANTHROPIC_DEFAULT_HAIKU_MODEL
+	SmallFast string `yaml:"small_fast,omitempty"` // ANTHROPIC_SMALL_FAST_MODEL（后台任务，旧版 Claude Code）
+}
+
+// RoleNames 支持的模型角色名称
+var RoleNames = []string{"opus", "sonnet", "haiku", "small_fast"}
+
+// Empty 判断是否未配置任何角色
+func (r ModelRoles) Empty() bool {
+	return r == ModelRoles{}
+}
+
+// Get 按名称获取角色对应的模型
+func (r ModelRoles) Get(role string) string {
+	switch role {
+	case "opus":
+		return r.Opus
+	case "sonnet":
+		return r.Sonnet
+	case "haiku":
+		return r.Haiku
+	case "small_fast":
+		return r.SmallFast
+	}
+	return ""
+}
+
+// Set 按名称设置角色对应的模型，角色名称无效时返回 false
+func (r *ModelRoles) Set(role, model string) bool {
+	switch role {
+	case "opus":
+		r.Opus = model
+	case "sonnet":
+		r.Sonnet = model
+	case "haiku":
+		r.Haiku = model
+	case "small_fast":
+		r.SmallFast = model
+	default:
+		return false
+	}
+	return true
+}
+
+// EffectiveRoles 获取实际使用的角色映射，未配置时沿用同名预置供应商的映射；
+// 显式配置的空映射（roles: {}）不会回退到预置映射
+func (p Provider) EffectiveRoles() ModelRoles {
+	if p.Roles != nil {
+		return *p.Roles
+	}
+	if preset, ok := Presets[p.Name]; ok && preset.Roles != nil {
+		return *preset.Roles
+	}
+	return ModelRoles{}
+}
+
+// EnvVar 环境变量
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// ModelEnv 返回 Claude Code 的模型环境变量
+// haiku 和 small_fast 只配置了其中一个时互相补全，兼容新旧版本的 Claude Code
+func (p Provider) ModelEnv() []EnvVar {
+	roles := p.EffectiveRoles()
+	if roles.SmallFast == "" {
+		roles.SmallFast = roles.Haiku
+	}
+	if roles.Haiku == "" {
+		roles.Haiku = roles.SmallFast
+	}
+
+	env := []EnvVar{{"ANTHROPIC_MODEL", p.Model}}
+	for _, v := range []EnvVar{
+		{"ANTHROPIC_DEFAULT_OPUS_MODEL", roles.Opus},
+		{"ANTHROPIC_DEFAULT_SONNET_MODEL", roles.Sonnet},
+		{"ANTHROPIC_DEFAULT_HAIKU_MODEL", roles.Haiku},
+		{"ANTHROPIC_SMALL_FAST_MODEL", roles.SmallFast},
+	} {
+		if v.Value != "" {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
+// ExtraEnv 返回供应商配置的额外环境变量（按名称排序）
+func (p Provider) ExtraEnv() []EnvVar {
+	keys := make([]string, 0, len(p.Env))
+	for k := range p.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]EnvVar, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, EnvVar{k, p.Env[k]})
+	}
+	return env
+}
+
+// CustomHeaders 返回 ANTHROPIC_CUSTOM_HEADERS 格式的附加请求头（每行一个 "Name: Value"）
+func (p Provider) CustomHeaders() string {
+	names := make([]string, 0, len(p.Headers))
+	for name := range p.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, name+": "+p.Headers[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ApplyHeaders 将供应商配置的附加请求头写入 h
+func (p Provider) ApplyHeaders(h http.Header) {
+	for name, value := range p.Headers {
+		h.Set(name, value)
+	}
+}
+
+// KnownModels 返回供应商声明的所有模型（默认模型、模型列表和角色映射，去重）
+func (p Provider) KnownModels() []string {
+	roles := p.EffectiveRoles()
+	candidates := append([]string{p.Model}, p.Models...)
+	candidates = append(candidates, roles.Opus, roles.Sonnet, roles.Haiku, roles.SmallFast)
+
+	var models []string
+	seen := map[string]bool{}
+	for _, m := range candidates {
+		if m != "" && !seen[m] {
+			seen[m] = true
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// ResolveModel 将请求中的模型映射为供应商模型
+// 供应商已声明的模型原样保留；Claude 模型名按 opus/sonnet/haiku 角色映射；其他情况使用默认模型
+func (p Provider) ResolveModel(requested string) string {
+	for _, m := range p.KnownModels() {
+		if m == requested {
+			return requested
+		}
+	}
+
+	roles := p.EffectiveRoles()
+	lower := strings.ToLower(requested)
+	switch {
+	case strings.Contains(lower, "haiku") && roles.Haiku != "":
+		return roles.Haiku
+	case strings.Contains(lower, "haiku") && roles.SmallFast != "":
+		return roles.SmallFast
+	case strings.Contains(lower, "opus") && roles.Opus != "":
+		return roles.Opus
+	case strings.Contains(lower, "sonnet") && roles.Sonnet != "":
+		return roles.Sonnet
+	}
+	return p.Model
+}
+
+// Pricing 模型单价，单位为每百万 token 的价格
+type Pricing struct {
+	Input      float64 `yaml:"input"`                 // 输入（未命中缓存）
+	Output     float64 `yaml:"output"`                // 输出
+	CacheWrite float64 `yaml:"cache_write,omitempty"` // 写入缓存，为 0 时按输入价格计算
+	CacheRead  float64 `yaml:"cache_read,omitempty"`  // 命中缓存，为 0 时按输入价格计算
+	Currency   string  `yaml:"currency,omitempty"`    // 计价货币，默认 USD
+}
+
+// Cost 按单价计算一次调用的费用（以计价货币表示）
+func (p Pricing) Cost(input, output, cacheWrite, cacheRead int64) float64 {
+	cw, cr := p.CacheWrite, p.CacheRead
+	if cw == 0 {
+		cw = p.Input
+	}
+	if cr == 0 {
+		cr = p.Input
+	}
+	return (float64(input)*p.Input + float64(output)*p.Output +
+		float64(cacheWrite)*cw + float64(cacheRead)*cr) / 1e6
+}
+
+// EffectiveCurrency 获取计价货币
+func (p Pricing) EffectiveCurrency() string {
+	if p.Currency == "" {
+		return "USD"
+	}
+	return p.Currency
+}
+
+// EffectivePricing 获取实际使用的单价，未配置时沿用同名预置供应商的参考价格
+func (p Provider) EffectivePricing() (Pricing, bool) {
+	if p.Pricing != nil {
+		return *p.Pricing, true
+	}
+	if preset, ok := Presets[p.Name]; ok && preset.Pricing != nil {
+		return *preset.Pricing, true
+	}
+	return Pricing{}, false
+}
+
+// ModelPricing 获取模型实际使用的单价，优先使用按模型配置的单价，其次为供应商的默认单价
+func (p Provider) ModelPricing(model string) (Pricing, bool) {
+	if pricing, ok := MatchPrice(p.ModelPrices, model); ok {
+		return pricing, true
+	}
+	return p.EffectivePricing()
+}
+
+// MatchPrice 按模型名前缀查找单价（最长前缀优先）
+func MatchPrice(prices map[string]Pricing, model string) (Pricing, bool) {
+	var best string
+	found := false
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	if !found {
+		return Pricing{}, false
+	}
+	return prices[best], true
+}
+
+// EffectiveProtocol 获取实际使用的 API 协议
+// 未显式配置时，仅在仍使用预置地址时沿用同名预置供应商的协议，旧配置文件无需迁移
+// 改为厂商 Anthropic 兼容地址的旧配置按 anthropic 处理
+func (p Provider) EffectiveProtocol() Protocol {
+	if p.Protocol != "" {
+		return p.Protocol
+	}
+	preset, ok := Presets[p.Name]
+	if ok && preset.Protocol != "" && (p.BaseURL == "" || strings.TrimRight(p.BaseURL, "/") == strings.TrimRight(preset.BaseURL, "/")) {
+		return preset.Protocol
+	}
+	return ProtocolAnthropic
 }
 
 // 预置供应商列表（用户只需填 API Key）
+// 预置单价仅供费用估算参考，以供应商官网为准
 var Presets = map[string]Provider{
 	"doubao": {
 		Name:        "doubao",
@@ -36,8 +375,10 @@ var Presets = map[string]Provider{
 		DisplayName: "DeepSeek（深度求索）",
 		BaseURL:     "https://api.deepseek.com",
 		Model:       "deepseek-chat",
+		Models:      []string{"deepseek-chat", "deepseek-reasoner"},
 		KeyURL:      "https://platform.deepseek.com",
 		Type:        TypeNativeModel,
+		Pricing:     &Pricing{Input: 2, Output: 3, CacheRead: 0.2, Currency: "CNY"},
 	},
 	"qwen": {
 		Name:        "qwen",
@@ -46,6 +387,8 @@ var Presets = map[string]Provider{
 		Model:       "qwen-max",
 		KeyURL:      "https://dashscope.console.aliyun.com",
 		Type:        TypeNativeModel,
+		Protocol:    ProtocolOpenAI,
+		Pricing:     &Pricing{Input: 2.4, Output: 9.6, Currency: "CNY"},
 	},
 	"kimi": {
 		Name:        "kimi",
@@ -54,6 +397,7 @@ var Presets = map[string]Provider{
 		Model:       "moonshot-v1-auto",
 		KeyURL:      "https://platform.moonshot.cn",
 		Type:        TypeNativeModel,
+		Protocol:    ProtocolOpenAI,
 	},
 	"siliconflow": {
 		Name:        "siliconflow",
@@ -62,6 +406,8 @@ var Presets = map[string]Provider{
 		Model:       "deepseek-ai/DeepSeek-V3",
 		KeyURL:      "https://cloud.siliconflow.cn",
 		Type:        TypeNativeModel,
+		Protocol:    ProtocolOpenAI,
+		Pricing:     &Pricing{Input: 2, Output: 8, Currency: "CNY"},
 	},
 	"glm": {
 		Name:        "glm",
@@ -70,14 +416,22 @@ var Presets = map[string]Provider{
 		Model:       "glm-4-plus",
 		KeyURL:      "https://open.bigmodel.cn",
 		Type:        TypeNativeModel,
+		Protocol:    ProtocolOpenAI,
+		Pricing:     &Pricing{Input: 5, Output: 5, Currency: "CNY"},
 	},
 	"wanjie": {
 		Name:        "wanjie",
 		DisplayName: "万界（Claude 代理）",
 		BaseURL:     "https://maas-openapi.wanjiedata.com/api/anthropic",
 		Model:       "claude-opus-4-5-20251101",
-		KeyURL:      "https://maas-openapi.wanjiedata.com",
-		Type:        TypeProxy,
+		Models:      []string{"claude-opus-4-5-20251101", "claude-sonnet-4-5-20250929", "claude-haiku-4-5-20251001"},
+		Roles: &ModelRoles{
+			Opus:   "claude-opus-4-5-20251101",
+			Sonnet: "claude-sonnet-4-5-20250929",
+			Haiku:  "claude-haiku-4-5-20251001",
+		},
+		KeyURL: "https://maas-openapi.wanjiedata.com",
+		Type:   TypeProxy,
 	},
 }
 
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEffectiveRoles(t *testing.T) {
+	preset := *Presets["wanjie"].Roles
+	tests := []struct {
+		name string
+		yaml string
+		want ModelRoles
+	}{
+		{name: "inherit preset", yaml: "name: wanjie\n", want: preset},
+		{name: "override preset", yaml: "name: wanjie\nroles:\n  opus: x\n", want: ModelRoles{Opus: "x"}},
+		{name: "explicit empty", yaml: "name: wanjie\nroles: {}\n", want: ModelRoles{}},
+		{name: "custom provider", yaml: "name: custom\n", want: ModelRoles{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provider
+			if err := yaml.Unmarshal([]byte(tt.yaml), &p); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := p.EffectiveRoles(); got != tt.want {
+				t.Errorf("EffectiveRoles = %+v, want %+v", got, tt.want)
+			}
+
+			// 保存后重新加载仍保持相同的映射
+			data, err := yaml.Marshal(p)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var reloaded Provider
+			if err := yaml.Unmarshal(data, &reloaded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := reloaded.EffectiveRoles(); got != tt.want {
+				t.Errorf("after round trip EffectiveRoles = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
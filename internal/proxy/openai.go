@@ -0,0 +1,758 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ccm/internal/provider"
+)
+
+// Anthropic Messages API 请求结构（仅包含转换所需字段）
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	MaxTokens     int                  `json:"max_tokens"`
+	System        json.RawMessage      `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	Source    *imageSource    `json:"source,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// OpenAI Chat Completions API 结构
+type openAIRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	MaxTokens     int             `json:"max_tokens,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
+	Stop          []string        `json:"stop,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+	StreamOptions *streamOptions  `json:"stream_options,omitempty"`
+	Tools         []openAITool    `json:"tools,omitempty"`
+	ToolChoice    any             `json:"tool_choice,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+	// Error 部分供应商在流中途以 {"error": {...}} 报告错误，也可能是字符串
+	Error json.RawMessage `json:"error"`
+}
+
+// forwardOpenAI 将 Anthropic 请求转换为 Chat Completions 请求并转发，
+// 返回已转换为 Anthropic 格式的响应
+func (s *Server) forwardOpenAI(r *http.Request, p *provider.Provider, apiKey, path string, body []byte) (*http.Response, error) {
+	switch {
+	case strings.HasSuffix(path, "/v1/messages/count_tokens"):
+		// Chat Completions 没有对应接口，按字节数粗略估算
+		return jsonResponse(http.StatusOK, map[string]int{"input_tokens": len(body) / 4}), nil
+	case strings.HasSuffix(path, "/v1/messages"):
+	default:
+		return jsonResponse(http.StatusNotFound, errorBody("not_found_error",
+			fmt.Sprintf("OpenAI 协议供应商不支持 %s", path))), nil
+	}
+
+	var areq anthropicRequest
+	if err := json.Unmarshal(body, &areq); err != nil {
+		return jsonResponse(http.StatusBadRequest, errorBody("invalid_request_error", err.Error())), nil
+	}
+
+	oreq, err := toOpenAIRequest(&areq)
+	if err != nil {
+		return jsonResponse(http.StatusBadRequest, errorBody("invalid_request_error", err.Error())), nil
+	}
+
+	payload, err := json.Marshal(oreq)
+	if err != nil {
+		return nil, err
+	}
+
+	target := strings.TrimRight(p.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.Authorize(req, apiKey)
+	p.ApplyHeaders(req.Header)
+	if areq.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromOpenAIResponse(resp, areq.Model, areq.Stream), nil
+}
+
+// toOpenAIRequest 转换请求体
+func toOpenAIRequest(areq *anthropicRequest) (*openAIRequest, error) {
+	oreq := &openAIRequest{
+		Model:       areq.Model,
+		MaxTokens:   areq.MaxTokens,
+		Temperature: areq.Temperature,
+		TopP:        areq.TopP,
+		Stop:        areq.StopSequences,
+		Stream:      areq.Stream,
+	}
+	if areq.Stream {
+		oreq.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	// system 可以是字符串或文本块数组
+	if len(areq.System) > 0 {
+		blocks, err := parseBlocks(areq.System)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析 system: %w", err)
+		}
+		if text := joinText(blocks); text != "" {
+			oreq.Messages = append(oreq.Messages, openAIMessage{Role: "system", Content: text})
+		}
+	}
+
+	for _, m := range areq.Messages {
+		blocks, err := parseBlocks(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析消息内容: %w", err)
+		}
+		if m.Role == "assistant" {
+			oreq.Messages = append(oreq.Messages, assistantMessage(blocks))
+		} else {
+			oreq.Messages = append(oreq.Messages, userMessages(blocks)...)
+		}
+	}
+
+	for _, t := range areq.Tools {
+		oreq.Tools = append(oreq.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.InputSchema,
+			},
+		})
+	}
+
+	if areq.ToolChoice != nil && len(oreq.Tools) > 0 {
+		switch areq.ToolChoice.Type {
+		case "auto":
+			oreq.ToolChoice = "auto"
+		case "any":
+			oreq.ToolChoice = "required"
+		case "none":
+			oreq.ToolChoice = "none"
+		case "tool":
+			oreq.ToolChoice = map[string]any{
+				"type":     "function",
+				"function": map[string]string{"name": areq.ToolChoice.Name},
+			}
+		}
+	}
+
+	return oreq, nil
+}
+
+// assistantMessage 转换助手消息: text 合并为 content，tool_use 转为 tool_calls
+func assistantMessage(blocks []anthropicBlock) openAIMessage {
+	msg := openAIMessage{Role: "assistant"}
+	var text strings.Builder
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, openAIToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+	msg.Content = text.String()
+	return msg
+}
+
+// userMessages 转换用户消息: tool_result 拆分为独立的 tool 消息，且必须紧跟在 tool_calls 之后
+func userMessages(blocks []anthropicBlock) []openAIMessage {
+	var out []openAIMessage
+	var parts []openAIContentPart
+	hasImage := false
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "tool_result":
+			content := ""
+			if inner, err := parseBlocks(b.Content); err == nil {
+				content = joinText(inner)
+			}
+			if b.IsError {
+				content = "[error] " + content
+			}
+			out = append(out, openAIMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: content})
+		case "text":
+			parts = append(parts, openAIContentPart{Type: "text", Text: b.Text})
+		case "image":
+			if b.Source == nil {
+				continue
+			}
+			url := b.Source.URL
+			if b.Source.Type == "base64" {
+				url = "data:" + b.Source.MediaType + ";base64," + b.Source.Data
+			}
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+			hasImage = true
+		}
+	}
+
+	if len(parts) == 0 {
+		return out
+	}
+
+	// 纯文本时使用字符串形式，兼容性更好
+	if !hasImage {
+		var text strings.Builder
+		for i, p := range parts {
+			if i > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(p.Text)
+		}
+		return append(out, openAIMessage{Role: "user", Content: text.String()})
+	}
+	return append(out, openAIMessage{Role: "user", Content: parts})
+}
+
+// parseBlocks 解析内容，字符串视为单个文本块
+func parseBlocks(raw json.RawMessage) ([]anthropicBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// joinText 拼接文本块
+func joinText(blocks []anthropicBlock) string {
+	var texts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// stopReason 将 finish_reason 映射为 Anthropic stop_reason
+func stopReason(finish string) string {
+	switch finish {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	default:
+		return "end_turn"
+	}
+}
+
+// fromOpenAIResponse 将上游响应转换为 Anthropic 格式
+func fromOpenAIResponse(resp *http.Response, model string, stream bool) *http.Response {
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return jsonResponse(resp.StatusCode, errorBody(errorType(resp.StatusCode), upstreamMessage(data)))
+	}
+
+	if stream {
+		pr, pw := io.Pipe()
+		go func() {
+			defer resp.Body.Close()
+			t := &streamTranslator{w: pw, model: model}
+			pw.CloseWithError(t.run(resp.Body))
+		}()
+
+		out := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       pr,
+		}
+		out.Header.Set("Content-Type", "text/event-stream")
+		out.Header.Set("Cache-Control", "no-cache")
+		return out
+	}
+
+	defer resp.Body.Close()
+	var oresp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&oresp); err != nil {
+		return jsonResponse(http.StatusBadGateway, errorBody("api_error", "无法解析上游响应: "+err.Error()))
+	}
+
+	content := []map[string]any{}
+	finish := ""
+	if len(oresp.Choices) > 0 {
+		choice := oresp.Choices[0]
+		finish = choice.FinishReason
+		if choice.Message.Content != "" {
+			content = append(content, map[string]any{"type": "text", "text": choice.Message.Content})
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			content = append(content, map[string]any{
+				"type":  "tool_use",
+				"id":    toolID(tc.ID),
+				"name":  tc.Function.Name,
+				"input": parseArguments(tc.Function.Arguments),
+			})
+		}
+	}
+
+	return jsonResponse(http.StatusOK, map[string]any{
+		"id":            messageID(oresp.ID),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   stopReason(finish),
+		"stop_sequence": nil,
+		"usage":         usageBody(oresp.Usage),
+	})
+}
+
+// streamTranslator 将 Chat Completions SSE 流转换为 Anthropic SSE 事件
+type streamTranslator struct {
+	w     io.Writer
+	model string
+
+	started    bool
+	blockIndex int    // 下一个内容块序号
+	open       string // 当前打开的内容块类型: "", "text", "tool_use"
+	toolIndex  int    // 当前工具调用在上游的序号
+	toolID     string // 当前工具调用在上游的 id
+	finish     string
+	usage      *openAIUsage
+}
+
+func (t *streamTranslator) run(body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+			// 上游中途出错，发送 Anthropic error 事件后结束，客户端不会把截断的内容当作完整回复
+			return t.fail(chunk.Error)
+		}
+		if err := t.handle(&chunk); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return t.finishMessage()
+}
+
+func (t *streamTranslator) handle(chunk *openAIStreamChunk) error {
+	if err := t.start(chunk.ID); err != nil {
+		return err
+	}
+	if chunk.Usage != nil {
+		t.usage = chunk.Usage
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			if t.open != "text" {
+				if err := t.openBlock("text", map[string]any{"type": "text", "text": ""}); err != nil {
+					return err
+				}
+			}
+			if err := t.delta(map[string]any{"type": "text_delta", "text": choice.Delta.Content}); err != nil {
+				return err
+			}
+		}
+
+		for _, tc := range choice.Delta.ToolCalls {
+			idx := t.toolIndex
+			if tc.Index != nil {
+				idx = *tc.Index
+			}
+			// 新的工具调用: 序号或 id 发生变化（部分供应商在每个分片中重复 id）
+			if t.open != "tool_use" || idx != t.toolIndex || (tc.ID != "" && tc.ID != t.toolID) {
+				t.toolIndex = idx
+				t.toolID = tc.ID
+				if err := t.openBlock("tool_use", map[string]any{
+					"type":  "tool_use",
+					"id":    toolID(tc.ID),
+					"name":  tc.Function.Name,
+					"input": map[string]any{},
+				}); err != nil {
+					return err
+				}
+			}
+			if tc.Function.Arguments != "" {
+				if err := t.delta(map[string]any{"type": "input_json_delta", "partial_json": tc.Function.Arguments}); err != nil {
+					return err
+				}
+			}
+		}
+
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			t.finish = *choice.FinishReason
+		}
+	}
+	return nil
+}
+
+func (t *streamTranslator) start(id string) error {
+	if t.started {
+		return nil
+	}
+	t.started = true
+	return writeEvent(t.w, "message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            messageID(id),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         t.model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": 0, "output_tokens": 0},
+		},
+	})
+}
+
+func (t *streamTranslator) openBlock(kind string, block map[string]any) error {
+	if err := t.closeBlock(); err != nil {
+		return err
+	}
+	t.open = kind
+	return writeEvent(t.w, "content_block_start", map[string]any{
+		"type":          "content_block_start",
+		"index":         t.blockIndex,
+		"content_block": block,
+	})
+}
+
+func (t *streamTranslator) delta(delta map[string]any) error {
+	return writeEvent(t.w, "content_block_delta", map[string]any{
+		"type":  "content_block_delta",
+		"index": t.blockIndex,
+		"delta": delta,
+	})
+}
+
+func (t *streamTranslator) closeBlock() error {
+	if t.open == "" {
+		return nil
+	}
+	t.open = ""
+	err := writeEvent(t.w, "content_block_stop", map[string]any{
+		"type":  "content_block_stop",
+		"index": t.blockIndex,
+	})
+	t.blockIndex++
+	return err
+}
+
+func (t *streamTranslator) finishMessage() error {
+	if err := t.start(""); err != nil {
+		return err
+	}
+	if err := t.closeBlock(); err != nil {
+		return err
+	}
+	if err := writeEvent(t.w, "message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": stopReason(t.finish), "stop_sequence": nil},
+		"usage": usageBody(t.usage),
+	}); err != nil {
+		return err
+	}
+	return writeEvent(t.w, "message_stop", map[string]any{"type": "message_stop"})
+}
+
+// fail 将上游流中的错误转换为 Anthropic error 事件
+func (t *streamTranslator) fail(raw json.RawMessage) error {
+	errType, message := "api_error", strings.TrimSpace(string(raw))
+	var text string
+	var body struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	}
+	switch {
+	case json.Unmarshal(raw, &text) == nil:
+		message = text
+	case json.Unmarshal(raw, &body) == nil:
+		if body.Message != "" {
+			message = body.Message
+		}
+		kind := strings.ToLower(body.Type + " " + fmt.Sprint(body.Code))
+		switch {
+		case strings.Contains(kind, "rate_limit") || strings.Contains(kind, "429"):
+			errType = "rate_limit_error"
+		case strings.Contains(kind, "overloaded") || strings.Contains(kind, "503"):
+			errType = "overloaded_error"
+		}
+	}
+	return writeEvent(t.w, "error", errorBody(errType, message))
+}
+
+// writeEvent 写入一个 SSE 事件
+func writeEvent(w io.Writer, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// usageBody 转换用量统计
+func usageBody(u *openAIUsage) map[string]int {
+	usage := map[string]int{"input_tokens": 0, "output_tokens": 0}
+	if u == nil {
+		return usage
+	}
+	usage["input_tokens"] = u.PromptTokens
+	usage["output_tokens"] = u.CompletionTokens
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		usage["input_tokens"] = u.PromptTokens - u.PromptTokensDetails.CachedTokens
+		usage["cache_read_input_tokens"] = u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
+// parseArguments 解析工具参数，非法 JSON 时返回空对象
+func parseArguments(args string) json.RawMessage {
+	if args == "" || !json.Valid([]byte(args)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+// upstreamMessage 提取 OpenAI 格式错误中的描述
+func upstreamMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error.Message != "" {
+		return body.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// errorType 将 HTTP 状态码映射为 Anthropic 错误类型
+func errorType(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return "invalid_request_error"
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == 529 || status == http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+func messageID(id string) string {
+	if id == "" {
+		return "msg_" + randomHex()
+	}
+	return "msg_" + strings.TrimPrefix(id, "chatcmpl-")
+}
+
+func toolID(id string) string {
+	if id == "" {
+		return "toolu_" + randomHex()
+	}
+	return id
+}
+
+func randomHex() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// jsonResponse 构造一个本地 JSON 响应
+func jsonResponse(status int, body any) *http.Response {
+	data, _ := json.Marshal(body)
+	resp := &http.Response{
+		StatusCode:    status,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}
+	resp.Header.Set("Content-Type", "application/json")
+	return resp
+}
+
+// errorBody 构造 Anthropic 格式的错误响应体
+func errorBody(errType, message string) map[string]any {
+	return map[string]any{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errType,
+			"message": message,
+		},
+	}
+}
@@ -0,0 +1,376 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToOpenAIRequest(t *testing.T) {
+	temp := 0.5
+	tests := []struct {
+		name string
+		req  string
+		want openAIRequest
+	}{
+		{
+			name: "system string and user text",
+			req:  `{"model":"m","max_tokens":100,"system":"be brief","messages":[{"role":"user","content":"hi"}]}`,
+			want: openAIRequest{Model: "m", MaxTokens: 100, Messages: []openAIMessage{
+				{Role: "system", Content: "be brief"},
+				{Role: "user", Content: "hi"},
+			}},
+		},
+		{
+			name: "system blocks, sampling and stop sequences",
+			req:  `{"model":"m","max_tokens":1,"temperature":0.5,"stop_sequences":["END"],"system":[{"type":"text","text":"a"},{"type":"text","text":"b"}],"messages":[{"role":"user","content":[{"type":"text","text":"x"},{"type":"text","text":"y"}]}]}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1, Temperature: &temp, Stop: []string{"END"}, Messages: []openAIMessage{
+				{Role: "system", Content: "a\nb"},
+				{Role: "user", Content: "x\ny"},
+			}},
+		},
+		{
+			name: "image becomes content parts",
+			req:  `{"model":"m","max_tokens":1,"messages":[{"role":"user","content":[{"type":"text","text":"what"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAA"}}]}]}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1, Messages: []openAIMessage{
+				{Role: "user", Content: []openAIContentPart{
+					{Type: "text", Text: "what"},
+					{Type: "image_url", ImageURL: &openAIImageURL{URL: "data:image/png;base64,AAA"}},
+				}},
+			}},
+		},
+		{
+			name: "tool use and tool result",
+			req: `{"model":"m","max_tokens":1,"messages":[
+				{"role":"assistant","content":[{"type":"text","text":"checking"},{"type":"tool_use","id":"toolu_1","name":"ls","input":{"dir":"/"}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"a b","is_error":true},{"type":"text","text":"next"}]}]}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1, Messages: []openAIMessage{
+				{Role: "assistant", Content: "checking", ToolCalls: []openAIToolCall{
+					{ID: "toolu_1", Type: "function", Function: openAIFunctionCall{Name: "ls", Arguments: `{"dir":"/"}`}},
+				}},
+				{Role: "tool", ToolCallID: "toolu_1", Content: "[error] a b"},
+				{Role: "user", Content: "next"},
+			}},
+		},
+		{
+			name: "tools and forced tool choice",
+			req:  `{"model":"m","max_tokens":1,"messages":[],"tools":[{"name":"ls","description":"list","input_schema":{"type":"object"}}],"tool_choice":{"type":"tool","name":"ls"}}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1,
+				Tools: []openAITool{{Type: "function", Function: openAIFunction{Name: "ls", Description: "list", Parameters: json.RawMessage(`{"type":"object"}`)}}},
+				ToolChoice: map[string]any{
+					"type":     "function",
+					"function": map[string]string{"name": "ls"},
+				},
+			},
+		},
+		{
+			name: "any tool choice is required",
+			req:  `{"model":"m","max_tokens":1,"messages":[],"tools":[{"name":"ls","input_schema":{}}],"tool_choice":{"type":"any"}}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1,
+				Tools:      []openAITool{{Type: "function", Function: openAIFunction{Name: "ls", Parameters: json.RawMessage(`{}`)}}},
+				ToolChoice: "required",
+			},
+		},
+		{
+			name: "stream asks for usage",
+			req:  `{"model":"m","max_tokens":1,"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			want: openAIRequest{Model: "m", MaxTokens: 1, Stream: true, StreamOptions: &streamOptions{IncludeUsage: true},
+				Messages: []openAIMessage{{Role: "user", Content: "hi"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var areq anthropicRequest
+			if err := json.Unmarshal([]byte(tt.req), &areq); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, err := toOpenAIRequest(&areq)
+			if err != nil {
+				t.Fatalf("toOpenAIRequest: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				t.Errorf("got  %s\nwant %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+// decodeBody 解析转换后的 JSON 响应
+func decodeBody(t *testing.T, resp *http.Response) map[string]any {
+	t.Helper()
+	defer resp.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return body
+}
+
+func upstream(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestFromOpenAIResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string // 期望的 Anthropic 响应 (JSON)，id 不比较
+	}{
+		{
+			name:   "text",
+			status: 200,
+			body:   `{"id":"chatcmpl-1","choices":[{"message":{"content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`,
+			want:   `{"type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":2}}`,
+		},
+		{
+			name:   "tool calls",
+			status: 200,
+			body:   `{"choices":[{"message":{"content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"ls","arguments":"{\"dir\":\"/\"}"}},{"id":"call_2","function":{"name":"pwd","arguments":"not json"}}]},"finish_reason":"tool_calls"}]}`,
+			want:   `{"type":"message","role":"assistant","model":"claude","content":[{"type":"tool_use","id":"call_1","name":"ls","input":{"dir":"/"}},{"type":"tool_use","id":"call_2","name":"pwd","input":{}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}`,
+		},
+		{
+			name:   "length and cached tokens",
+			status: 200,
+			body:   `{"choices":[{"message":{"content":"cut"},"finish_reason":"length"}],"usage":{"prompt_tokens":100,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":60}}}`,
+			want:   `{"type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"cut"}],"stop_reason":"max_tokens","stop_sequence":null,"usage":{"input_tokens":40,"output_tokens":5,"cache_read_input_tokens":60}}`,
+		},
+		{
+			name:   "upstream error",
+			status: 429,
+			body:   `{"error":{"message":"slow down","type":"rate_limit"}}`,
+			want:   `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`,
+		},
+		{
+			name:   "plain text error",
+			status: 500,
+			body:   "boom\n",
+			want:   `{"type":"error","error":{"type":"api_error","message":"boom"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := fromOpenAIResponse(upstream(tt.status, tt.body), "claude", false)
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			got := decodeBody(t, resp)
+			if id, _ := got["id"].(string); tt.status == 200 && !strings.HasPrefix(id, "msg_") {
+				t.Errorf("id = %q, want msg_ prefix", id)
+			}
+			delete(got, "id")
+			var want map[string]any
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("bad want: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Errorf("got  %s\nwant %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+// sseEvent 转换后的一个 Anthropic SSE 事件
+type sseEvent struct {
+	Name string
+	Data map[string]any
+}
+
+// translateStream 将上游 SSE 文本转换为 Anthropic 事件
+func translateStream(t *testing.T, chunks ...string) []sseEvent {
+	t.Helper()
+	var in strings.Builder
+	for _, c := range chunks {
+		in.WriteString("data: " + c + "\n\n")
+	}
+	resp := fromOpenAIResponse(upstream(200, in.String()), "claude", true)
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var events []sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	var name string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var data map[string]any
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("bad event data %q: %v", line, err)
+			}
+			events = append(events, sseEvent{Name: name, Data: data})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	return events
+}
+
+func eventNames(events []sseEvent) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// field 按路径读取事件数据中的字段
+func field(data map[string]any, path ...string) any {
+	var v any = data
+	for _, key := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func TestStreamTranslator(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		events []string
+		check  func(t *testing.T, events []sseEvent)
+	}{
+		{
+			name: "text",
+			chunks: []string{
+				`{"id":"chatcmpl-9","choices":[{"delta":{"content":"Hel"}}]}`,
+				`{"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+				`[DONE]`,
+			},
+			events: []string{"message_start", "content_block_start", "content_block_delta", "content_block_delta", "content_block_stop", "message_delta", "message_stop"},
+			check: func(t *testing.T, events []sseEvent) {
+				if id := field(events[0].Data, "message", "id"); id != "msg_9" {
+					t.Errorf("message id = %v", id)
+				}
+				if got := field(events[1].Data, "content_block", "type"); got != "text" {
+					t.Errorf("block type = %v", got)
+				}
+				if got := field(events[3].Data, "delta", "text"); got != "lo" {
+					t.Errorf("second delta = %v", got)
+				}
+				if got := field(events[5].Data, "delta", "stop_reason"); got != "end_turn" {
+					t.Errorf("stop_reason = %v", got)
+				}
+			},
+		},
+		{
+			name: "tool calls",
+			chunks: []string{
+				`{"choices":[{"delta":{"content":"let me look"}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"name":"ls","arguments":""}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"dir\":"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"/\"}"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_b","function":{"name":"pwd","arguments":"{}"}}]}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+				`[DONE]`,
+			},
+			events: []string{
+				"message_start",
+				"content_block_start", "content_block_delta", "content_block_stop",
+				"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+				"content_block_start", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			check: func(t *testing.T, events []sseEvent) {
+				if got := field(events[4].Data, "content_block", "id"); got != "call_a" {
+					t.Errorf("first tool id = %v", got)
+				}
+				if got := field(events[4].Data, "index"); got != float64(1) {
+					t.Errorf("first tool index = %v", got)
+				}
+				args := field(events[5].Data, "delta", "partial_json").(string) + field(events[6].Data, "delta", "partial_json").(string)
+				if args != `{"dir":"/"}` {
+					t.Errorf("tool arguments = %s", args)
+				}
+				if got := field(events[8].Data, "content_block", "name"); got != "pwd" {
+					t.Errorf("second tool name = %v", got)
+				}
+				if got := field(events[11].Data, "delta", "stop_reason"); got != "tool_use" {
+					t.Errorf("stop_reason = %v", got)
+				}
+			},
+		},
+		{
+			name: "finish reason length",
+			chunks: []string{
+				`{"choices":[{"delta":{"content":"a"},"finish_reason":"length"}]}`,
+			},
+			events: []string{"message_start", "content_block_start", "content_block_delta", "content_block_stop", "message_delta", "message_stop"},
+			check: func(t *testing.T, events []sseEvent) {
+				if got := field(events[4].Data, "delta", "stop_reason"); got != "max_tokens" {
+					t.Errorf("stop_reason = %v", got)
+				}
+			},
+		},
+		{
+			name: "usage chunk",
+			chunks: []string{
+				`{"choices":[{"delta":{"content":"a"},"finish_reason":"stop"}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":30,"completion_tokens":7,"prompt_tokens_details":{"cached_tokens":10}}}`,
+				`[DONE]`,
+			},
+			events: []string{"message_start", "content_block_start", "content_block_delta", "content_block_stop", "message_delta", "message_stop"},
+			check: func(t *testing.T, events []sseEvent) {
+				usage := field(events[4].Data, "usage")
+				want := map[string]any{"input_tokens": float64(20), "output_tokens": float64(7), "cache_read_input_tokens": float64(10)}
+				if !reflect.DeepEqual(usage, want) {
+					t.Errorf("usage = %v, want %v", usage, want)
+				}
+			},
+		},
+		{
+			name: "error mid-stream",
+			chunks: []string{
+				`{"choices":[{"delta":{"content":"partial"}}]}`,
+				`{"error":{"message":"upstream overloaded","type":"overloaded_error"}}`,
+				`{"choices":[{"delta":{"content":"never sent"}}]}`,
+			},
+			events: []string{"message_start", "content_block_start", "content_block_delta", "error"},
+			check: func(t *testing.T, events []sseEvent) {
+				e := events[3].Data
+				if field(e, "type") != "error" || field(e, "error", "type") != "overloaded_error" || field(e, "error", "message") != "upstream overloaded" {
+					t.Errorf("error event = %v", e)
+				}
+			},
+		},
+		{
+			name:   "string error before any content",
+			chunks: []string{`{"error":"quota exceeded"}`},
+			events: []string{"error"},
+			check: func(t *testing.T, events []sseEvent) {
+				if got := field(events[0].Data, "error", "message"); got != "quota exceeded" {
+					t.Errorf("message = %v", got)
+				}
+				if got := field(events[0].Data, "error", "type"); got != "api_error" {
+					t.Errorf("type = %v", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := translateStream(t, tt.chunks...)
+			if got := eventNames(events); !reflect.DeepEqual(got, tt.events) {
+				t.Fatalf("events = %v\nwant     %v", got, tt.events)
+			}
+			tt.check(t, events)
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+)
+
+// DefaultAddr 本地网关默认监听地址
+const DefaultAddr = "127.0.0.1:8765"
+
+// AnthropicVersion 发送 Messages API 请求时使用的版本头
+const AnthropicVersion = "2023-06-01"
+
+// providerPrefix 固定供应商的路由前缀: /providers/<name>/v1/messages
+const providerPrefix = "/providers/"
+
+// hopHeaders 不应转发的逐跳请求头
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Attempt 单次上游尝试
+type Attempt struct {
+	Provider string
+	Key      string // 配置了多个密钥时使用的密钥标签
+	Status   int
+	Err      error
+}
+
+// Record 单次请求的转发记录
+type Record struct {
+	Provider string // 实际完成请求的供应商
+	Key      string // 配置了多个密钥时使用的密钥标签
+	Method   string
+	Path     string
+	Status   int
+	Latency  time.Duration
+	Err      error
+	Attempts []Attempt // 故障转移过程中失败的尝试
+}
+
+// Server 本地 Anthropic 兼容网关
+type Server struct {
+	// Provider 固定使用的供应商，为空时每次请求读取当前默认供应商
+	Provider string
+	// Timeout 单次尝试等待响应头的超时时间，超时后切换到下一个供应商
+	Timeout time.Duration
+	// OnServed 每次请求结束后回调，用于输出日志
+	OnServed func(Record)
+	// Token 本次网关会话的访问令牌，客户端通过 Authorization 或 x-api-key 携带
+	Token string
+
+	client *http.Client
+}
+
+// DefaultTimeout 默认的单次尝试超时
+const DefaultTimeout = 2 * time.Minute
+
+// MaxRequestBody 请求体大小上限，足以容纳带图片和长上下文的请求
+const MaxRequestBody = 32 << 20
+
+// New 创建网关
+func New(providerName string) *Server {
+	return &Server{
+		Provider: providerName,
+		Timeout:  DefaultTimeout,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				DialContext:         (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// ListenAndServe 在指定地址启动网关，只允许监听本机回环地址
+func (s *Server) ListenAndServe(addr string) error {
+	if err := CheckListenAddr(addr); err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := Record{Method: r.Method, Path: r.URL.Path}
+	defer func() {
+		rec.Latency = time.Since(start)
+		if s.OnServed != nil {
+			s.OnServed(rec)
+		}
+	}()
+
+	if status, err := s.authorize(r); err != nil {
+		rec.Status, rec.Err = status, err
+		errType := "permission_error"
+		if status == http.StatusUnauthorized {
+			errType = "authentication_error"
+		}
+		writeError(w, status, errType, err.Error())
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		rec.Status = http.StatusInternalServerError
+		rec.Err = fmt.Errorf("加载配置失败: %w", err)
+		writeError(w, rec.Status, "api_error", rec.Err.Error())
+		return
+	}
+
+	name, path := s.route(cfg, r.URL.Path)
+	rec.Provider = name
+	if name == "" {
+		rec.Status = http.StatusServiceUnavailable
+		rec.Err = fmt.Errorf("未指定供应商，且未设置默认供应商")
+		writeError(w, rec.Status, "api_error", rec.Err.Error())
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			rec.Status = http.StatusRequestEntityTooLarge
+			rec.Err = fmt.Errorf("请求体超过 %d MiB 上限", MaxRequestBody>>20)
+			writeError(w, rec.Status, "request_too_large", rec.Err.Error())
+			return
+		}
+		rec.Status = http.StatusBadRequest
+		rec.Err = err
+		writeError(w, rec.Status, "invalid_request_error", err.Error())
+		return
+	}
+
+	// 依次尝试故障转移链上的供应商，429/5xx/超时/网络错误时切换到下一个
+	// 上一个可重试的响应保留到收到新的响应为止，后续供应商都不可用时仍返回给客户端
+	var last *http.Response
+	var lastCancel context.CancelFunc
+	lastAttempt := -1 // last 在 rec.Attempts 中的下标
+	for _, candidate := range cfg.FailoverChain(name) {
+		p, src, err := resolve(cfg, candidate)
+		if err != nil {
+			rec.Attempts = append(rec.Attempts, Attempt{Provider: candidate, Err: err})
+			continue
+		}
+
+		payload := body
+		if r.Method == http.MethodPost && isMessagesPath(path) {
+			payload = rewriteModel(body, p)
+		}
+
+		resp, cancel, label, err := s.attemptKeys(r, &rec, cfg, candidate, p, src, path, payload)
+		if err != nil {
+			rec.Attempts = append(rec.Attempts, Attempt{Provider: candidate, Key: label, Err: err})
+			if r.Context().Err() != nil {
+				// 客户端已断开，不再重试
+				break
+			}
+			continue
+		}
+
+		if last != nil {
+			last.Body.Close()
+			lastCancel()
+		}
+		last, lastCancel = resp, cancel
+		rec.Provider, rec.Key = candidate, label
+		if !retryable(resp.StatusCode) {
+			lastAttempt = -1
+			break
+		}
+		lastAttempt = len(rec.Attempts)
+		rec.Attempts = append(rec.Attempts, Attempt{Provider: candidate, Key: label, Status: resp.StatusCode})
+	}
+
+	if last == nil {
+		rec.Status = http.StatusBadGateway
+		if n := len(rec.Attempts); n > 0 {
+			rec.Provider = rec.Attempts[n-1].Provider
+			rec.Key = rec.Attempts[n-1].Key
+			rec.Err = rec.Attempts[n-1].Err
+			rec.Attempts = rec.Attempts[:n-1]
+		}
+		writeError(w, rec.Status, "api_error", fmt.Sprintf("所有供应商均不可用: %v", rec.Err))
+		return
+	}
+	defer lastCancel()
+	defer last.Body.Close()
+	if lastAttempt >= 0 {
+		// 返回给客户端的响应不再作为失败的尝试记录
+		rec.Attempts = append(rec.Attempts[:lastAttempt], rec.Attempts[lastAttempt+1:]...)
+	}
+
+	rec.Status = last.StatusCode
+	w.Header().Set("X-Ccm-Provider", rec.Provider)
+	copyResponse(w, last)
+}
+
+// attemptKeys 使用供应商的密钥发起请求，返回响应和使用的密钥标签
+// 密钥被限流、耗尽或吊销时换用同一供应商的下一个密钥重试，配置了多个密钥时记录失效的密钥，
+// 当前密钥都不可用时使用轮换下来且尚未过期的旧密钥
+func (s *Server) attemptKeys(r *http.Request, rec *Record, cfg *config.Config, name string, p *provider.Provider, src config.KeySource, path string, body []byte) (*http.Response, context.CancelFunc, string, error) {
+	pool, _ := src.(*config.KeyPool)
+	retired := config.RetiredPool(cfg, name)
+	// useRetired 换用旧密钥，没有旧密钥时返回 false
+	useRetired := func() bool {
+		if retired == nil {
+			return false
+		}
+		pool, retired = retired, nil
+		return true
+	}
+
+	apiKey, err := src.Resolve()
+	if err == nil && apiKey == "" {
+		err = fmt.Errorf("API Key 为空")
+	}
+	if err != nil && useRetired() {
+		apiKey, err = pool.Resolve()
+	}
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("获取 API Key 失败: %w", err)
+	}
+	for {
+		resp, cancel, err := s.attempt(r, p, apiKey, path, body)
+		label := ""
+		if pool != nil {
+			label = pool.Label()
+		}
+		if err != nil || !keyFailure(resp.StatusCode) {
+			return resp, cancel, label, err
+		}
+		if pool != nil {
+			reportKey(name, label, resp)
+		}
+
+		var next string
+		found := false
+		if pool != nil {
+			next, err = pool.Resolve()
+			found = err == nil
+		}
+		if !found && useRetired() {
+			next, err = pool.Resolve()
+			found = err == nil
+		}
+		if !found {
+			// 没有其他可用的密钥，返回本次响应，由调用方决定是否切换供应商
+			return resp, cancel, label, nil
+		}
+		rec.Attempts = append(rec.Attempts, Attempt{Provider: name, Key: label, Status: resp.StatusCode})
+		resp.Body.Close()
+		cancel()
+		apiKey = next
+	}
+}
+
+// keyFailure 判断响应是否表示密钥被限流、耗尽或吊销，应换用其他密钥
+func keyFailure(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// reportKey 根据上游响应记录密钥状态
+// 401 或提示密钥无效的 403 视为吊销，402 或提示余额不足视为耗尽，其余 429 记录为限流
+// 其他 403 可能只是模型或地区无权限，仅本次请求换用其他密钥
+func reportKey(name, label string, resp *http.Response) {
+	// 读取错误响应以判断原因，再放回供后续转发
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	message := string(data)
+
+	// 状态写入失败不影响转发
+	reason := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusPaymentRequired || provider.IsQuotaError(message):
+		_ = config.MarkKey(name, label, config.KeyExhausted, reason)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		_ = config.RecordRateLimit(name, label)
+	case resp.StatusCode == http.StatusUnauthorized || provider.IsInvalidKeyError(message):
+		_ = config.MarkKey(name, label, config.KeyRevoked, reason)
+	}
+}
+
+// attempt 向单个供应商发起请求，超过 Timeout 仍未收到响应头则视为失败
+// 成功时返回的 cancel 需在响应体读取完毕后调用
+func (s *Server) attempt(r *http.Request, p *provider.Provider, apiKey, path string, body []byte) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	timer := time.AfterFunc(s.Timeout, cancel)
+
+	resp, err := s.forward(r.WithContext(ctx), p, apiKey, path, body)
+	if !timer.Stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, nil, fmt.Errorf("等待响应超时 (%s)", s.Timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
+
+// retryable 判断状态码是否应切换到下一个供应商
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// route 解析请求路径，返回目标供应商和去掉前缀后的上游路径
+func (s *Server) route(cfg *config.Config, path string) (string, string) {
+	if strings.HasPrefix(path, providerPrefix) {
+		rest := strings.TrimPrefix(path, providerPrefix)
+		name, sub, _ := strings.Cut(rest, "/")
+		return name, "/" + sub
+	}
+	if s.Provider != "" {
+		return s.Provider, path
+	}
+	return cfg.Default, path
+}
+
+// resolve 读取供应商配置和 API Key 来源（每次请求重新加载，切换后无需重启）
+func resolve(cfg *config.Config, name string) (*provider.Provider, config.KeySource, error) {
+	p, ok := cfg.Providers[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("供应商 '%s' 未配置", name)
+	}
+
+	src := config.FindKeySource(cfg, name)
+	if src == nil {
+		return nil, nil, fmt.Errorf("供应商 '%s' 未设置 API Key", name)
+	}
+
+	return &p, src, nil
+}
+
+// forward 将请求转发到供应商，注入真实的 API Key
+// OpenAI 协议的供应商会先转换请求，响应也会转换回 Anthropic 格式
+func (s *Server) forward(r *http.Request, p *provider.Provider, apiKey, path string, body []byte) (*http.Response, error) {
+	if p.EffectiveProtocol() == provider.ProtocolOpenAI {
+		return s.forwardOpenAI(r, p, apiKey, path, body)
+	}
+
+	target := strings.TrimRight(p.BaseURL, "/") + path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = r.Header.Clone()
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	// 由 Transport 负责压缩协商，便于流式转发
+	req.Header.Del("Accept-Encoding")
+	// 丢弃客户端携带的占位凭据
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("Authorization")
+	p.Authorize(req, apiKey)
+	p.ApplyHeaders(req.Header)
+
+	return s.client.Do(req)
+}
+
+// copyResponse 将上游响应写回客户端，支持 SSE 流式输出
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	for _, h := range hopHeaders {
+		w.Header().Del(h)
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isMessagesPath 判断是否为 Messages API 请求
+func isMessagesPath(path string) bool {
+	return strings.HasSuffix(path, "/v1/messages") || strings.HasSuffix(path, "/v1/messages/count_tokens")
+}
+
+// rewriteModel 将请求体中的模型映射为供应商的模型
+// 供应商声明过的模型保持不变，便于 Claude Code 按角色使用不同模型
+func rewriteModel(body []byte, p *provider.Provider) []byte {
+	if p.Model == "" {
+		return body
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+
+	var requested string
+	_ = json.Unmarshal(payload["model"], &requested)
+	model := p.ResolveModel(requested)
+	if model == requested {
+		return body
+	}
+
+	encoded, _ := json.Marshal(model)
+	payload["model"] = encoded
+
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// writeError 以 Anthropic 错误格式返回
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorBody(errType, message))
+}
+
+// sender 供 Send 使用的共享网关实例
+var sender = New("")
+
+// Send 以 Anthropic Messages 格式直接向供应商发送请求（不经过本地监听）
+// OpenAI 协议的供应商同样会进行协议转换，行为与经网关访问一致
+func Send(ctx context.Context, p *provider.Provider, apiKey string, body []byte) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/messages", nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("anthropic-version", AnthropicVersion)
+	return sender.forward(r, p, apiKey, "/v1/messages", body)
+}
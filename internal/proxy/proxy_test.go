@@ -0,0 +1,314 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+)
+
+const testToken = "ccm-test-token"
+
+// fakeProvider 模拟供应商，按请求携带的 API Key 返回状态码（默认 200），并记录收到的密钥
+type fakeProvider struct {
+	*httptest.Server
+	mu   sync.Mutex
+	keys []string
+}
+
+func newFakeProvider(t *testing.T, statuses map[string]int) *fakeProvider {
+	t.Helper()
+	f := &fakeProvider{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		f.mu.Lock()
+		f.keys = append(f.keys, key)
+		f.mu.Unlock()
+
+		status, ok := statuses[key]
+		if !ok {
+			status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"key": key})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// received 返回供应商依次收到的 API Key
+func (f *fakeProvider) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.keys...)
+}
+
+// testGateway 将配置保存到临时目录并创建网关
+func testGateway(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	orig := config.GetConfigDir()
+	config.SetConfigDir(t.TempDir())
+	t.Cleanup(func() { config.SetConfigDir(orig) })
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+
+	s := New("")
+	s.Token = testToken
+	return s
+}
+
+// messagesRequest 构造携带网关令牌的本机 Messages 请求
+func messagesRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	r.Host = DefaultAddr
+	r.Header.Set("X-Api-Key", testToken)
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// serve 通过网关处理请求，返回响应和转发记录
+func serve(s *Server, r *http.Request) (*httptest.ResponseRecorder, Record) {
+	var rec Record
+	s.OnServed = func(r Record) { rec = r }
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w, rec
+}
+
+// responseErrorType 解析 Anthropic 错误响应的类型
+func responseErrorType(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body %q: %v", w.Body.String(), err)
+	}
+	return body.Error.Type
+}
+
+func TestAuthorize(t *testing.T) {
+	up := newFakeProvider(t, nil)
+	s := testGateway(t, &config.Config{
+		Default: "a",
+		Providers: map[string]provider.Provider{
+			"a": {Name: "a", BaseURL: up.URL, APIKey: "ka"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(r *http.Request)
+		status  int
+		errType string
+	}{
+		{name: "x-api-key", modify: func(r *http.Request) {}, status: http.StatusOK},
+		{name: "bearer", modify: func(r *http.Request) {
+			r.Header.Del("X-Api-Key")
+			r.Header.Set("Authorization", "Bearer "+testToken)
+		}, status: http.StatusOK},
+		{name: "localhost", modify: func(r *http.Request) { r.Host = "localhost:8765" }, status: http.StatusOK},
+		{name: "ipv6 loopback", modify: func(r *http.Request) { r.Host = "[::1]:8765" }, status: http.StatusOK},
+		{name: "missing token", modify: func(r *http.Request) { r.Header.Del("X-Api-Key") },
+			status: http.StatusUnauthorized, errType: "authentication_error"},
+		{name: "wrong token", modify: func(r *http.Request) { r.Header.Set("X-Api-Key", "ccm-other") },
+			status: http.StatusUnauthorized, errType: "authentication_error"},
+		{name: "provider key as token", modify: func(r *http.Request) { r.Header.Set("X-Api-Key", "ka") },
+			status: http.StatusUnauthorized, errType: "authentication_error"},
+		{name: "remote host", modify: func(r *http.Request) { r.Host = "evil.example:8765" },
+			status: http.StatusForbidden, errType: "permission_error"},
+		{name: "rebinding host", modify: func(r *http.Request) { r.Host = "localhost.evil.example" },
+			status: http.StatusForbidden, errType: "permission_error"},
+		{name: "browser origin", modify: func(r *http.Request) { r.Header.Set("Origin", "http://localhost:3000") },
+			status: http.StatusForbidden, errType: "permission_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(up.received())
+			r := messagesRequest(`{"model":"claude"}`)
+			tt.modify(r)
+			w, _ := serve(s, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			received := up.received()[before:]
+			if tt.errType == "" {
+				// 转发时使用供应商的密钥，不泄露网关令牌
+				if !reflect.DeepEqual(received, []string{"ka"}) {
+					t.Errorf("upstream received keys %q, want [ka]", received)
+				}
+				return
+			}
+			if got := responseErrorType(t, w); got != tt.errType {
+				t.Errorf("error type = %q, want %q", got, tt.errType)
+			}
+			if len(received) > 0 {
+				t.Errorf("rejected request reached upstream with keys %q", received)
+			}
+		})
+	}
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	up := newFakeProvider(t, nil)
+	s := testGateway(t, &config.Config{
+		Default: "a",
+		Providers: map[string]provider.Provider{
+			"a": {Name: "a", BaseURL: up.URL, APIKey: "ka"},
+		},
+	})
+
+	w, rec := serve(s, messagesRequest(strings.Repeat("x", MaxRequestBody+1)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", w.Code)
+	}
+	if got := responseErrorType(t, w); got != "request_too_large" {
+		t.Errorf("error type = %q, want request_too_large", got)
+	}
+	if rec.Status != http.StatusRequestEntityTooLarge || rec.Err == nil {
+		t.Errorf("record = %+v", rec)
+	}
+	if keys := up.received(); len(keys) > 0 {
+		t.Errorf("oversized request reached upstream with keys %q", keys)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses map[string]int // 各供应商的密钥返回的状态码
+		status   int
+		provider string
+		attempts []string // 失败尝试的供应商
+		keys     []string // 上游依次收到的密钥
+	}{
+		{
+			name:     "ok",
+			status:   http.StatusOK,
+			provider: "a",
+			keys:     []string{"ka"},
+		},
+		{
+			name:     "rate limited",
+			statuses: map[string]int{"ka": http.StatusTooManyRequests},
+			status:   http.StatusOK,
+			provider: "b",
+			attempts: []string{"a"},
+			keys:     []string{"ka", "kb"},
+		},
+		{
+			name:     "server errors",
+			statuses: map[string]int{"ka": http.StatusServiceUnavailable, "kb": http.StatusInternalServerError},
+			status:   http.StatusOK,
+			provider: "c",
+			attempts: []string{"a", "b"},
+			keys:     []string{"ka", "kb", "kc"},
+		},
+		{
+			name:     "client error not retried",
+			statuses: map[string]int{"ka": http.StatusBadRequest},
+			status:   http.StatusBadRequest,
+			provider: "a",
+			keys:     []string{"ka"},
+		},
+		{
+			name:     "all unavailable",
+			statuses: map[string]int{"ka": http.StatusTooManyRequests, "kb": http.StatusBadGateway, "kc": http.StatusServiceUnavailable},
+			status:   http.StatusServiceUnavailable,
+			provider: "c",
+			attempts: []string{"a", "b"},
+			keys:     []string{"ka", "kb", "kc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newFakeProvider(t, tt.statuses)
+			s := testGateway(t, &config.Config{
+				Default:  "a",
+				Fallback: []string{"b", "c"},
+				Providers: map[string]provider.Provider{
+					"a": {Name: "a", BaseURL: up.URL, APIKey: "ka"},
+					"b": {Name: "b", BaseURL: up.URL, APIKey: "kb"},
+					"c": {Name: "c", BaseURL: up.URL, APIKey: "kc"},
+				},
+			})
+
+			w, rec := serve(s, messagesRequest(`{"model":"claude"}`))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("X-Ccm-Provider"); got != tt.provider {
+				t.Errorf("X-Ccm-Provider = %q, want %q", got, tt.provider)
+			}
+			if rec.Provider != tt.provider || rec.Status != tt.status {
+				t.Errorf("record provider/status = %s/%d, want %s/%d", rec.Provider, rec.Status, tt.provider, tt.status)
+			}
+			var attempts []string
+			for _, a := range rec.Attempts {
+				attempts = append(attempts, a.Provider)
+			}
+			if !reflect.DeepEqual(attempts, tt.attempts) {
+				t.Errorf("attempts = %q, want %q", attempts, tt.attempts)
+			}
+			if got := up.received(); !reflect.DeepEqual(got, tt.keys) {
+				t.Errorf("upstream received keys %q, want %q", got, tt.keys)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	up := newFakeProvider(t, map[string]int{"k1": http.StatusTooManyRequests, "k2": http.StatusUnauthorized})
+	s := testGateway(t, &config.Config{
+		Default: "a",
+		Providers: map[string]provider.Provider{
+			"a": {Name: "a", BaseURL: up.URL, Keys: []provider.APIKeyEntry{
+				{Label: "one", Key: "k1"},
+				{Label: "two", Key: "k2"},
+				{Label: "three", Key: "k3"},
+			}},
+		},
+	})
+
+	// 被限流和吊销的密钥在同一请求内换用下一个
+	w, rec := serve(s, messagesRequest(`{"model":"claude"}`))
+	if w.Code != http.StatusOK || rec.Key != "three" {
+		t.Fatalf("served %d with key %q, want 200 with three", w.Code, rec.Key)
+	}
+	if got, want := up.received(), []string{"k1", "k2", "k3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("upstream received keys %q, want %q", got, want)
+	}
+	var attempts []string
+	for _, a := range rec.Attempts {
+		attempts = append(attempts, a.Key)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("failed attempts = %q, want %q", attempts, want)
+	}
+
+	states := config.KeyStates("a")
+	if states["one"].Last429.IsZero() || states["one"].Status != config.KeyActive {
+		t.Errorf("rate limited key state = %+v", states["one"])
+	}
+	if states["two"].Status != config.KeyRevoked {
+		t.Errorf("revoked key state = %+v", states["two"])
+	}
+
+	// 吊销的密钥不再使用，轮换从上次使用的下一个密钥开始
+	before := len(up.received())
+	serve(s, messagesRequest(`{"model":"claude"}`))
+	if got, want := up.received()[before:], []string{"k1", "k3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second request sent keys %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ccm/internal/config"
+)
+
+// TokenFile 网关访问令牌文件，按监听地址区分，仅所有者可读
+// ccm serve 启动时生成新的令牌，ccm run --proxy 等命令读取后传给 Claude Code
+func TokenFile(addr string) string {
+	name := strings.NewReplacer(":", "_", "[", "", "]", "").Replace(addr)
+	return filepath.Join(config.GetConfigDir(), "gateway-"+name+".token")
+}
+
+// NewToken 为本次网关会话生成随机令牌并保存
+func NewToken(addr string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := "ccm-" + hex.EncodeToString(b)
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(TokenFile(addr), []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// LoadToken 读取网关当前会话的令牌
+func LoadToken(addr string) (string, error) {
+	data, err := os.ReadFile(TokenFile(addr))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("未找到网关 %s 的访问令牌，请先运行 'ccm serve'", addr)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// CheckListenAddr 检查监听地址是否为本机回环地址
+// 网关使用真实的 API Key 转发请求，不能暴露给其他主机
+func CheckListenAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if !isLoopback(host) {
+		return fmt.Errorf("网关只能监听本机回环地址 (如 %s)，不能监听 '%s'", DefaultAddr, addr)
+	}
+	return nil
+}
+
+// authorize 校验请求来自本机的 ccm 会话
+// Host 必须是回环地址以防止 DNS 重绑定，不接受浏览器的跨域请求，并且需要携带本次网关会话的令牌
+func (s *Server) authorize(r *http.Request) (int, error) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !isLoopback(host) {
+		return http.StatusForbidden, fmt.Errorf("拒绝非本机地址的请求 (Host: %s)", r.Host)
+	}
+	if r.Header.Get("Origin") != "" {
+		return http.StatusForbidden, errors.New("拒绝浏览器发起的跨域请求")
+	}
+
+	token := r.Header.Get("X-Api-Key")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+		return http.StatusUnauthorized, errors.New("缺少或错误的网关访问令牌")
+	}
+	return 0, nil
+}
+
+// isLoopback 检查主机名是否为本机回环地址
+func isLoopback(host string) bool {
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,7 +1,11 @@
 package app
 
 import (
+	"fmt"
+	"time"
+
 	"ccm/internal/config"
+	"ccm/internal/probe"
 	"ccm/internal/provider"
 	"ccm/internal/ui/components"
 	"ccm/internal/ui/dialogs"
@@ -16,6 +20,7 @@ type AppModel struct {
 	// Data
 	config    *config.Config
 	providers []components.ProviderListItem
+	health    map[string][]probe.HealthRecord
 
 	// Components
 	header       components.HeaderModel
@@ -33,6 +38,11 @@ type AppModel struct {
 	ready      bool
 	quitting   bool
 	runCommand string // Provider to run after quit
+
+	// Test-all progress
+	batchPending map[string]bool // Providers still being tested
+	batchTotal   int
+	batchFailed  int
 }
 
 // NewApp creates a new app model
@@ -48,13 +58,20 @@ func NewApp() AppModel {
 		}
 	}
 
+	// Load connection test history
+	health, err := probe.LoadHealth()
+	if err != nil {
+		health = map[string][]probe.HealthRecord{}
+	}
+
 	// Build provider items
-	items := buildProviderItems(cfg)
+	items := buildProviderItems(cfg, health)
 
 	// Create components
 	m := AppModel{
 		config:       cfg,
 		providers:    items,
+		health:       health,
 		header:       components.NewHeader(),
 		providerList: components.NewProviderListSimple(items),
 		detailPanel:  components.NewDetailPanel(),
@@ -63,25 +80,20 @@ func NewApp() AppModel {
 
 	// Set initial state
 	m.statusBar.SetDefaultProvider(cfg.Default)
+	m.statusBar.SetKeyWarning(keyWarningText(cfg))
 	m.statusBar.SetThemeIcon(theme.Current.IsDark)
 
 	// Update detail panel with first provider
-	if len(items) > 0 {
-		if selected := m.providerList.Selected(); selected != nil {
-			if p, exists := cfg.Providers[selected.Name]; exists {
-				m.detailPanel.SetProvider(&p)
-			} else if preset, exists := provider.Presets[selected.Name]; exists {
-				m.detailPanel.SetProvider(&preset)
-			}
-		}
-	}
+	m.updateDetailPanel()
 
 	return m
 }
 
-// buildProviderItems converts config to list items
-func buildProviderItems(cfg *config.Config) []components.ProviderListItem {
+// buildProviderItems converts config to list items, restoring the last
+// recorded connection status of each provider
+func buildProviderItems(cfg *config.Config, health map[string][]probe.HealthRecord) []components.ProviderListItem {
 	items := []components.ProviderListItem{}
+	reports, _ := probe.LoadCapabilities()
 
 	// Add presets in order
 	for _, name := range provider.PresetOrder {
@@ -90,7 +102,7 @@ func buildProviderItems(cfg *config.Config) []components.ProviderListItem {
 		isDefault := false
 
 		// Check if configured in config
-		if cp, exists := cfg.Providers[name]; exists && cp.APIKey != "" {
+		if cp, exists := cfg.Providers[name]; exists && cp.HasAPIKey() {
 			isConfigured = true
 		}
 
@@ -104,12 +116,15 @@ func buildProviderItems(cfg *config.Config) []components.ProviderListItem {
 			isDefault = true
 		}
 
+		status, latency := lastStatus(health[name])
 		items = append(items, components.ProviderListItem{
 			Name:         name,
 			DisplayName:  p.DisplayName,
 			IsConfigured: isConfigured,
 			IsDefault:    isDefault,
-			Status:       messages.ConnectionUnknown,
+			Status:       status,
+			Latency:      latency,
+			Capabilities: capabilityBadges(reports, name),
 		})
 	}
 
@@ -117,12 +132,15 @@ func buildProviderItems(cfg *config.Config) []components.ProviderListItem {
 	for name, p := range cfg.Providers {
 		if _, exists := provider.Presets[name]; !exists {
 			isDefault := cfg.Default == name
+			status, latency := lastStatus(health[name])
 			items = append(items, components.ProviderListItem{
 				Name:         name,
 				DisplayName:  p.DisplayName,
 				IsConfigured: true,
 				IsDefault:    isDefault,
-				Status:       messages.ConnectionUnknown,
+				Status:       status,
+				Latency:      latency,
+				Capabilities: capabilityBadges(reports, name),
 			})
 		}
 	}
@@ -130,6 +148,94 @@ func buildProviderItems(cfg *config.Config) []components.ProviderListItem {
 	return items
 }
 
+// capabilityBadges converts a cached deep test report to list badges
+func capabilityBadges(reports map[string]probe.CapabilityReport, name string) []components.CapabilityBadge {
+	report, ok := reports[name]
+	if !ok {
+		return nil
+	}
+
+	badges := []components.CapabilityBadge{}
+	for _, c := range probe.Capabilities {
+		if passed, checked := report.Passed(c); checked {
+			badges = append(badges, components.CapabilityBadge{Label: c.Badge(), OK: passed})
+		}
+	}
+	return badges
+}
+
+// lastStatus returns the connection status of the most recent recorded test
+func lastStatus(records []probe.HealthRecord) (messages.ConnectionStatus, time.Duration) {
+	if len(records) == 0 {
+		return messages.ConnectionUnknown, 0
+	}
+	last := records[len(records)-1]
+	if !last.OK() {
+		return messages.ConnectionError, last.Latency()
+	}
+	return messages.ConnectionOK, last.Latency()
+}
+
+// keySourceLabel describes where a provider's API key comes from, without the key itself
+func keySourceLabel(cfg *config.Config, name string) string {
+	src := config.FindKeySource(cfg, name)
+	if src == nil {
+		return ""
+	}
+	switch src.Kind() {
+	case config.KeySourceVault:
+		return "vault (encrypted)"
+	case config.KeySourceConfig:
+		return "providers.yaml"
+	case config.KeySourcePool:
+		p := cfg.Providers[name]
+		return fmt.Sprintf("%d keys (%s)", len(p.Keys), p.EffectiveKeyStrategy())
+	}
+	if detail := src.Detail(); detail != "" {
+		return src.Kind() + ": " + detail
+	}
+	return src.Kind()
+}
+
+// keyWarningText summarizes keys that are near expiry or past the rotation age
+func keyWarningText(cfg *config.Config) string {
+	warnings := cfg.AllKeyWarnings(time.Now())
+	if len(warnings) == 0 {
+		return ""
+	}
+	if len(warnings) > 1 {
+		return fmt.Sprintf("⚠ %d keys need rotation", len(warnings))
+	}
+
+	w := warnings[0]
+	target := w.Provider
+	if w.Label != "" {
+		target += "/" + w.Label
+	}
+	switch w.Kind {
+	case config.KeyExpired:
+		return "⚠ " + target + " key expired"
+	case config.KeyExpiring:
+		return fmt.Sprintf("⚠ %s key expires in %dd", target, w.Days)
+	}
+	return fmt.Sprintf("⚠ %s key is %dd old", target, w.Days)
+}
+
+// healthSummary converts recorded tests to the detail panel summary
+func healthSummary(records []probe.HealthRecord) components.HealthSummary {
+	since := time.Now().Add(-probe.HealthWindow)
+	uptime, tests := probe.Uptime(records, since)
+	summary := components.HealthSummary{Uptime: uptime, Tests: tests, Window: "7d"}
+
+	for _, r := range records {
+		if r.Time.Before(since) {
+			continue
+		}
+		summary.Recent = append(summary.Recent, components.HealthSample{OK: r.OK(), Latency: r.Latency()})
+	}
+	return summary
+}
+
 // GetRunCommand returns the provider to run after quit
 func (m AppModel) GetRunCommand() string {
 	return m.runCommand
@@ -1,18 +1,25 @@
 package app
 
 import (
-	"net/http"
-	"time"
+	"context"
+	"fmt"
 
 	"ccm/internal/config"
+	"ccm/internal/probe"
 	"ccm/internal/ui/messages"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// testConnection tests the connection to a provider
+// probeSlots bounds how many connection tests run at once
+var probeSlots = make(chan struct{}, probe.DefaultWorkers)
+
+// testConnection tests the connection to a provider with a real Messages API request
 func testConnection(name string) tea.Cmd {
 	return func() tea.Msg {
+		probeSlots <- struct{}{}
+		defer func() { <-probeSlots }()
+
 		cfg, err := config.Load()
 		if err != nil {
 			return messages.ConnectionResultMsg{
@@ -27,27 +34,12 @@ func testConnection(name string) tea.Cmd {
 			return messages.ConnectionResultMsg{
 				Name:   name,
 				Status: messages.ConnectionError,
-				Error:  err,
-			}
-		}
-
-		// Get effective API key
-		apiKey := config.GetEffectiveAPIKey(name)
-		if apiKey == "" {
-			return messages.ConnectionResultMsg{
-				Name:   name,
-				Status: messages.ConnectionError,
-				Error:  err,
+				Error:  fmt.Errorf("provider not configured"),
 			}
 		}
 
-		// Create HTTP client with timeout
-		client := &http.Client{
-			Timeout: 10 * time.Second,
-		}
-
-		// Create request
-		req, err := http.NewRequest("GET", p.BaseURL, nil)
+		// Resolve the API key, reporting why it could not be obtained
+		apiKey, _, err := config.ResolveAPIKey(name)
 		if err != nil {
 			return messages.ConnectionResultMsg{
 				Name:   name,
@@ -56,40 +48,57 @@ func testConnection(name string) tea.Cmd {
 			}
 		}
 
-		// Add authorization header
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-		req.Header.Set("Content-Type", "application/json")
-
-		// Execute request and measure latency
-		start := time.Now()
-		resp, err := client.Do(req)
-		latency := time.Since(start)
-
-		if err != nil {
+		result := probe.Probe(context.Background(), p, apiKey)
+		_ = probe.RecordHealth(name, result)
+		if !result.OK() {
 			return messages.ConnectionResultMsg{
 				Name:    name,
 				Status:  messages.ConnectionError,
-				Latency: latency,
-				Error:   err,
-			}
-		}
-		defer resp.Body.Close()
-
-		// Check response status
-		if resp.StatusCode >= 400 && resp.StatusCode != 404 {
-			// 404 is often returned by API endpoints that don't support GET
-			// We still consider it a successful connection
-			return messages.ConnectionResultMsg{
-				Name:    name,
-				Status:  messages.ConnectionError,
-				Latency: latency,
+				Latency: result.Latency,
+				Error:   result.AsError(),
 			}
 		}
 
 		return messages.ConnectionResultMsg{
 			Name:    name,
 			Status:  messages.ConnectionOK,
-			Latency: latency,
+			Latency: result.Latency,
+		}
+	}
+}
+
+// testAllConnections tests every configured provider concurrently
+func testAllConnections(names []string) tea.Cmd {
+	cmds := make([]tea.Cmd, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, testConnection(name))
+	}
+	return tea.Batch(cmds...)
+}
+
+// fetchModels queries a provider's models endpoint for edit dialog autocompletion.
+// Endpoint failures are ignored: many providers do not expose a models endpoint.
+// A key that cannot be resolved is reported in the status bar.
+func fetchModels(name string) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil
+		}
+		p, exists := cfg.Providers[name]
+		if !exists || config.FindKeySource(cfg, name) == nil {
+			return nil
+		}
+		apiKey, _, err := config.ResolveAPIKey(name)
+		if err != nil {
+			return modelsLoadedMsg{name: name, err: err}
+		}
+
+		models, err := probe.ListModels(context.Background(), p, apiKey)
+		if err != nil || len(models) == 0 {
+			return nil
 		}
+		_ = probe.SaveModelCache(name, models)
+		return modelsLoadedMsg{name: name, models: models}
 	}
 }
@@ -1,7 +1,10 @@
 package app
 
 import (
+	"fmt"
+
 	"ccm/internal/config"
+	"ccm/internal/probe"
 	"ccm/internal/provider"
 	"ccm/internal/ui/dialogs"
 	"ccm/internal/ui/messages"
@@ -22,7 +25,7 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Update component sizes
 		m.header.SetWidth(msg.Width)
-		m.providerList.SetSize(msg.Width, msg.Height-8) // header + detail + status
+		m.providerList.SetSize(msg.Width, msg.Height-10) // header + detail + status
 		m.detailPanel.SetWidth(msg.Width)
 		m.statusBar.SetWidth(msg.Width)
 
@@ -44,10 +47,18 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case messages.ConnectionResultMsg:
 		m.providerList.UpdateConnectionStatus(msg.Name, msg.Status, msg.Latency)
+		if health, err := probe.LoadHealth(); err == nil {
+			m.health = health
+		}
 
 		// Update detail panel if this is the selected provider
 		if selected := m.providerList.Selected(); selected != nil && selected.Name == msg.Name {
 			m.detailPanel.SetConnectionStatus(msg.Status, msg.Latency, msg.Error)
+			m.detailPanel.SetHealth(healthSummary(m.health[msg.Name]))
+		}
+
+		if m.batchPending[msg.Name] {
+			m.recordBatchResult(msg)
 		}
 
 		return m, nil
@@ -73,7 +84,8 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case providerSavedMsg:
 		m.config = msg.config
-		m.providers = buildProviderItems(msg.config)
+		m.statusBar.SetKeyWarning(keyWarningText(msg.config))
+		m.providers = buildProviderItems(msg.config, m.health)
 		m.providerList.SetItems(m.providers)
 		m.statusBar.SetMessage("Provider saved: "+msg.name, false)
 		m.updateDetailPanel()
@@ -81,15 +93,28 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case providerRemovedMsg:
 		m.config = msg.config
-		m.providers = buildProviderItems(msg.config)
+		m.statusBar.SetKeyWarning(keyWarningText(msg.config))
+		m.providers = buildProviderItems(msg.config, m.health)
 		m.providerList.SetItems(m.providers)
 		m.statusBar.SetMessage("Provider removed: "+msg.name, false)
 		m.updateDetailPanel()
 		return m, nil
 
+	case modelsLoadedMsg:
+		if msg.err != nil {
+			m.statusBar.SetMessage("Cannot load models: "+msg.err.Error(), true)
+			return m, nil
+		}
+		if dialog, ok := m.activeDialog.(dialogs.EditDialogModel); ok && dialog.ProviderName() == msg.name {
+			dialog.SetModelSuggestions(msg.models)
+			m.activeDialog = dialog
+		}
+		return m, nil
+
 	case defaultSetMsg:
 		m.config = msg.config
-		m.providers = buildProviderItems(msg.config)
+		m.statusBar.SetKeyWarning(keyWarningText(msg.config))
+		m.providers = buildProviderItems(msg.config, m.health)
 		m.providerList.SetItems(m.providers)
 		m.statusBar.SetDefaultProvider(msg.name)
 		m.statusBar.SetMessage("Default set: "+msg.name, false)
@@ -186,6 +211,34 @@ func (m AppModel) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.detailPanel.SetConnectionStatus(messages.ConnectionTesting, 0, nil)
 		return m, testConnection(selected.Name)
 
+	case "T":
+		// Test all configured providers
+		if len(m.batchPending) > 0 {
+			return m, nil
+		}
+		var names []string
+		for _, item := range m.providers {
+			if item.IsConfigured {
+				names = append(names, item.Name)
+			}
+		}
+		if len(names) == 0 {
+			m.statusBar.SetMessage("No configured providers to test", true)
+			return m, nil
+		}
+		m.batchPending = make(map[string]bool, len(names))
+		for _, name := range names {
+			m.batchPending[name] = true
+			m.providerList.UpdateConnectionStatus(name, messages.ConnectionTesting, 0)
+		}
+		m.batchTotal = len(names)
+		m.batchFailed = 0
+		if selected := m.providerList.Selected(); selected != nil && m.batchPending[selected.Name] {
+			m.detailPanel.SetConnectionStatus(messages.ConnectionTesting, 0, nil)
+		}
+		m.statusBar.SetMessage(fmt.Sprintf("Testing %d providers...", len(names)), false)
+		return m, testAllConnections(names)
+
 	case "r":
 		// Remove provider
 		selected := m.providerList.Selected()
@@ -202,6 +255,24 @@ func (m AppModel) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// recordBatchResult updates test-all progress in the status bar
+func (m *AppModel) recordBatchResult(msg messages.ConnectionResultMsg) {
+	delete(m.batchPending, msg.Name)
+	if msg.Status != messages.ConnectionOK {
+		m.batchFailed++
+	}
+
+	done := m.batchTotal - len(m.batchPending)
+	switch {
+	case len(m.batchPending) > 0:
+		m.statusBar.SetMessage(fmt.Sprintf("Testing providers... %d/%d", done, m.batchTotal), false)
+	case m.batchFailed > 0:
+		m.statusBar.SetMessage(fmt.Sprintf("%d/%d providers failed", m.batchFailed, m.batchTotal), true)
+	default:
+		m.statusBar.SetMessage(fmt.Sprintf("All %d providers OK", m.batchTotal), false)
+	}
+}
+
 func (m AppModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.providerList, cmd = m.providerList.Update(msg)
@@ -251,6 +322,10 @@ func (m *AppModel) updateDetailPanel() {
 		return
 	}
 
+	m.detailPanel.SetConnectionStatus(selected.Status, selected.Latency, nil)
+	m.detailPanel.SetHealth(healthSummary(m.health[selected.Name]))
+	m.detailPanel.SetKeySource(keySourceLabel(m.config, selected.Name))
+
 	// Try to get from config first
 	if p, exists := m.config.Providers[selected.Name]; exists {
 		m.detailPanel.SetProvider(&p)
@@ -272,14 +347,27 @@ func (m AppModel) openEditDialog(name string) (tea.Model, tea.Cmd) {
 	// Get existing config or preset
 	if cp, exists := m.config.Providers[name]; exists {
 		p = cp
+		// Show the decrypted key; a locked vault keeps the ciphertext, which saves back unchanged
+		if apiKey, err := config.RevealAPIKey(m.config, name); err == nil {
+			p.APIKey = apiKey
+		}
 	} else if preset, exists := provider.Presets[name]; exists {
 		p = preset
 	} else {
 		return m, nil
 	}
 
-	m.activeDialog = dialogs.NewEditDialog(p)
+	dialog := dialogs.NewEditDialog(p)
+	if caches, err := probe.LoadModelCache(); err == nil {
+		dialog.SetModelSuggestions(caches[name].Models)
+	}
+	m.activeDialog = dialog
 	m.dialogType = messages.DialogEdit
+
+	// Refresh model suggestions from the provider's models endpoint
+	if _, exists := m.config.Providers[name]; exists {
+		return m, fetchModels(name)
+	}
 	return m, nil
 }
 
@@ -352,3 +440,9 @@ type defaultSetMsg struct {
 	config *config.Config
 	name   string
 }
+
+type modelsLoadedMsg struct {
+	name   string
+	models []string
+	err    error // API key could not be resolved
+}
@@ -12,12 +12,31 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// HealthSample is one past connection test result
+type HealthSample struct {
+	OK      bool
+	Latency time.Duration
+}
+
+// HealthSummary is the recorded test history of a provider
+type HealthSummary struct {
+	Recent []HealthSample // Most recent tests, oldest first
+	Uptime float64        // Success percentage over the window
+	Tests  int            // Number of tests in the window
+	Window string         // Window label, e.g. "7d"
+}
+
+// sparkBlocks are the sparkline levels from lowest to highest latency
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
 // DetailPanelModel shows details of the selected provider
 type DetailPanelModel struct {
 	provider   *provider.Provider
+	keySource  string
 	status     messages.ConnectionStatus
 	latency    time.Duration
 	statusText string
+	health     HealthSummary
 	width      int
 }
 
@@ -31,6 +50,11 @@ func (m *DetailPanelModel) SetProvider(p *provider.Provider) {
 	m.provider = p
 }
 
+// SetKeySource updates the description of where the API key comes from
+func (m *DetailPanelModel) SetKeySource(source string) {
+	m.keySource = source
+}
+
 // SetConnectionStatus updates the connection status
 func (m *DetailPanelModel) SetConnectionStatus(status messages.ConnectionStatus, latency time.Duration, err error) {
 	m.status = status
@@ -42,6 +66,11 @@ func (m *DetailPanelModel) SetConnectionStatus(status messages.ConnectionStatus,
 	}
 }
 
+// SetHealth updates the connection test history
+func (m *DetailPanelModel) SetHealth(health HealthSummary) {
+	m.health = health
+}
+
 // SetWidth updates the panel width
 func (m *DetailPanelModel) SetWidth(width int) {
 	m.width = width
@@ -82,6 +111,16 @@ func (m DetailPanelModel) View() string {
 	b.WriteString(urlStyle.Render(m.provider.BaseURL))
 	b.WriteString("\n")
 
+	// API key source (never the key itself)
+	b.WriteString(labelStyle.Render("Key:"))
+	b.WriteString(" ")
+	if m.keySource != "" {
+		b.WriteString(m.keySource)
+	} else {
+		b.WriteString(styles.Muted.Render("Not set"))
+	}
+	b.WriteString("\n")
+
 	// Connection Status
 	b.WriteString(labelStyle.Render("Status:"))
 	b.WriteString(" ")
@@ -104,5 +143,64 @@ func (m DetailPanelModel) View() string {
 	}
 	b.WriteString("\n")
 
+	// Health history
+	b.WriteString(labelStyle.Render("Health:"))
+	b.WriteString(" ")
+	b.WriteString(m.healthView())
+	b.WriteString("\n")
+
 	return b.String()
 }
+
+// healthView renders the latency sparkline and uptime
+func (m DetailPanelModel) healthView() string {
+	styles := theme.GetStyles()
+
+	if m.health.Tests == 0 {
+		return styles.Muted.Render("No tests in the last " + m.health.Window)
+	}
+
+	// Keep the sparkline within the panel width
+	samples := m.health.Recent
+	if limit := m.width - 40; limit > 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+
+	var lo, hi time.Duration
+	for _, s := range samples {
+		if !s.OK {
+			continue
+		}
+		if lo == 0 || s.Latency < lo {
+			lo = s.Latency
+		}
+		if s.Latency > hi {
+			hi = s.Latency
+		}
+	}
+
+	var spark strings.Builder
+	for _, s := range samples {
+		if !s.OK {
+			spark.WriteString(styles.Error.Render("×"))
+			continue
+		}
+		level := 0
+		if hi > lo {
+			level = int(float64(s.Latency-lo) / float64(hi-lo) * float64(len(sparkBlocks)-1))
+		}
+		spark.WriteString(styles.Success.Render(string(sparkBlocks[level])))
+	}
+
+	uptimeStyle := styles.Success
+	switch {
+	case m.health.Uptime < 90:
+		uptimeStyle = styles.Error
+	case m.health.Uptime < 99:
+		uptimeStyle = lipgloss.NewStyle().Foreground(theme.Current.Warning)
+	}
+
+	return spark.String() + "  " +
+		uptimeStyle.Render(fmt.Sprintf("%.1f%% uptime", m.health.Uptime)) +
+		styles.Muted.Render(fmt.Sprintf(" (%d tests, %s)", m.health.Tests, m.health.Window))
+}
@@ -0,0 +1,165 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"ccm/internal/ui/styles"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pickerHeight is the number of visible items in the picker
+const pickerHeight = 12
+
+// PickerModel is a filterable single-choice list (type to filter)
+type PickerModel struct {
+	label    string
+	items    []string
+	marked   string // Item shown with a ★ (e.g. current value)
+	filtered []string
+	filter   textinput.Model
+	cursor   int
+	offset   int
+	selected string
+	quitting bool
+	canceled bool
+}
+
+// NewPicker creates a new picker, placing the cursor on the marked item
+func NewPicker(items []string, marked, label string) PickerModel {
+	ti := textinput.New()
+	ti.Placeholder = "type to filter..."
+	ti.CharLimit = 128
+	ti.Width = 40
+	ti.Focus()
+
+	m := PickerModel{
+		label:  label,
+		items:  items,
+		marked: marked,
+		filter: ti,
+	}
+	m.applyFilter()
+	for i, item := range m.filtered {
+		if item == marked {
+			m.cursor = i
+			m.ensureVisible()
+		}
+	}
+	return m
+}
+
+// Init implements tea.Model
+func (m PickerModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update implements tea.Model
+func (m PickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "ctrl+p":
+			if m.cursor > 0 {
+				m.cursor--
+				m.ensureVisible()
+			}
+			return m, nil
+		case "down", "ctrl+n":
+			if m.cursor < len(m.filtered)-1 {
+				m.cursor++
+				m.ensureVisible()
+			}
+			return m, nil
+		case "enter":
+			if len(m.filtered) == 0 {
+				return m, nil
+			}
+			m.selected = m.filtered[m.cursor]
+			m.quitting = true
+			return m, tea.Quit
+		case "ctrl+c", "esc":
+			m.canceled = true
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.applyFilter()
+	return m, cmd
+}
+
+func (m *PickerModel) applyFilter() {
+	query := strings.ToLower(m.filter.Value())
+	m.filtered = m.filtered[:0:0]
+	for _, item := range m.items {
+		if query == "" || strings.Contains(strings.ToLower(item), query) {
+			m.filtered = append(m.filtered, item)
+		}
+	}
+	if m.cursor >= len(m.filtered) {
+		m.cursor = max(0, len(m.filtered)-1)
+	}
+	m.ensureVisible()
+}
+
+func (m *PickerModel) ensureVisible() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+pickerHeight {
+		m.offset = m.cursor - pickerHeight + 1
+	}
+}
+
+// View implements tea.Model
+func (m PickerModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	var b strings.Builder
+
+	// Label and filter
+	b.WriteString(styles.PromptStyle.Render(m.label))
+	b.WriteString("\n")
+	b.WriteString(m.filter.View())
+	b.WriteString("\n")
+
+	// Items
+	end := min(m.offset+pickerHeight, len(m.filtered))
+	for i := m.offset; i < end; i++ {
+		item := m.filtered[i]
+		cursor := "  "
+		style := styles.NormalItemStyle
+		if i == m.cursor {
+			cursor = styles.CursorStyle.Render("▸ ")
+			style = styles.SelectedItemStyle
+		}
+		line := cursor + style.Render(item)
+		if item == m.marked {
+			line += styles.DefaultMarkerStyle.Render(" ★")
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(m.filtered) == 0 {
+		b.WriteString(styles.MutedStyle.Render("  (no match)\n"))
+	}
+
+	// Help
+	b.WriteString(styles.MutedStyle.Render(fmt.Sprintf("\n%d/%d • ↑/↓: move • enter: select • esc: cancel", len(m.filtered), len(m.items))))
+
+	return b.String()
+}
+
+// Selected returns the selected item
+func (m PickerModel) Selected() string {
+	return m.selected
+}
+
+// Canceled returns whether the user canceled
+func (m PickerModel) Canceled() bool {
+	return m.canceled
+}
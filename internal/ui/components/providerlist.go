@@ -16,6 +16,12 @@ import (
 // ProviderItem is an alias for backward compatibility with ui.go
 type ProviderItem = ProviderListItem
 
+// CapabilityBadge is a cached capability check result shown next to a provider
+type CapabilityBadge struct {
+	Label string
+	OK    bool
+}
+
 // ProviderListItem represents a provider in the list
 type ProviderListItem struct {
 	Name         string
@@ -24,6 +30,7 @@ type ProviderListItem struct {
 	IsDefault    bool
 	Status       messages.ConnectionStatus
 	Latency      time.Duration
+	Capabilities []CapabilityBadge
 }
 
 // ProviderListModel is the provider selection list with search
@@ -342,17 +349,31 @@ func (m ProviderListModel) View() string {
 			connStatus = styles.Error.Render(" ●")
 		}
 
+		// Capability badges from the last deep test
+		badges := ""
+		if len(item.Capabilities) > 0 {
+			badges = " "
+			for _, c := range item.Capabilities {
+				if c.OK {
+					badges += styles.Success.Render(c.Label)
+				} else {
+					badges += styles.Error.Render(c.Label)
+				}
+			}
+		}
+
 		// Format line
 		name := nameStyle.Render(fmt.Sprintf("%-12s", item.Name))
 		displayName := styles.Muted.Render(item.DisplayName)
 
-		line := fmt.Sprintf("%s%s %s%s%s%s",
+		line := fmt.Sprintf("%s%s %s%s%s%s%s",
 			cursor,
 			name,
 			displayName,
 			status,
 			defaultMark,
 			connStatus,
+			badges,
 		)
 
 		b.WriteString(line)
@@ -12,6 +12,7 @@ type StatusBarModel struct {
 	message     string
 	isError     bool
 	defaultName string
+	keyWarning  string
 	themeIcon   string
 }
 
@@ -44,6 +45,11 @@ func (m *StatusBarModel) SetDefaultProvider(name string) {
 	m.defaultName = name
 }
 
+// SetKeyWarning sets the key rotation warning shown on the right side
+func (m *StatusBarModel) SetKeyWarning(text string) {
+	m.keyWarning = text
+}
+
 // SetThemeIcon updates the theme indicator
 func (m *StatusBarModel) SetThemeIcon(isDark bool) {
 	if isDark {
@@ -87,8 +93,12 @@ func (m StatusBarModel) View() string {
 		shortcutText += keyStyle.Render(s.key) + descStyle.Render(":"+s.desc)
 	}
 
-	// Right side: default provider and theme
+	// Right side: key warning, default provider and theme
 	rightContent := ""
+	if m.keyWarning != "" {
+		rightContent += lipgloss.NewStyle().Foreground(t.Error).Render(m.keyWarning)
+		rightContent += "  "
+	}
 	if m.defaultName != "" {
 		defaultStyle := lipgloss.NewStyle().
 			Foreground(t.Warning)
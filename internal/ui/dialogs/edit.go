@@ -3,11 +3,13 @@ package dialogs
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"ccm/internal/provider"
 	"ccm/internal/ui/messages"
 	"ccm/internal/ui/theme"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -45,6 +47,14 @@ func NewEditDialog(p provider.Provider) EditDialogModel {
 	if p.APIKey != "" {
 		fields[fieldAPIKey].SetValue(p.APIKey)
 	}
+	switch {
+	case len(p.Keys) > 0:
+		fields[fieldAPIKey].Placeholder = fmt.Sprintf("%d keys, managed by 'ccm keys'", len(p.Keys))
+	case p.APIKeyCmd != "":
+		fields[fieldAPIKey].Placeholder = "from api_key_cmd"
+	case p.APIKeyFile != "":
+		fields[fieldAPIKey].Placeholder = "from api_key_file"
+	}
 
 	// Base URL field
 	fields[fieldBaseURL] = textinput.New()
@@ -53,12 +63,17 @@ func NewEditDialog(p provider.Provider) EditDialogModel {
 	fields[fieldBaseURL].Width = 40
 	fields[fieldBaseURL].SetValue(p.BaseURL)
 
-	// Model field
+	// Model field, with autocomplete from known models
 	fields[fieldModel] = textinput.New()
 	fields[fieldModel].Placeholder = "model-name"
 	fields[fieldModel].CharLimit = 128
 	fields[fieldModel].Width = 40
 	fields[fieldModel].SetValue(p.Model)
+	fields[fieldModel].ShowSuggestions = true
+	fields[fieldModel].KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right", "ctrl+e"))
+	fields[fieldModel].KeyMap.NextSuggestion = key.NewBinding(key.WithKeys("ctrl+n"))
+	fields[fieldModel].KeyMap.PrevSuggestion = key.NewBinding(key.WithKeys("ctrl+p"))
+	fields[fieldModel].SetSuggestions(p.KnownModels())
 
 	// Focus first field
 	fields[fieldAPIKey].Focus()
@@ -68,8 +83,30 @@ func NewEditDialog(p provider.Provider) EditDialogModel {
 		fields:       fields,
 		focusedField: 0,
 		width:        60,
-		height:       14,
+		height:       16,
+	}
+}
+
+// SetModelSuggestions sets the model IDs offered for autocompletion,
+// keeping the provider's declared models first
+func (m *EditDialogModel) SetModelSuggestions(models []string) {
+	suggestions := m.provider.KnownModels()
+	seen := map[string]bool{}
+	for _, s := range suggestions {
+		seen[s] = true
+	}
+	for _, s := range models {
+		if !seen[s] {
+			seen[s] = true
+			suggestions = append(suggestions, s)
+		}
 	}
+	m.fields[fieldModel].SetSuggestions(suggestions)
+}
+
+// ProviderName returns the name of the provider being edited
+func (m EditDialogModel) ProviderName() string {
+	return m.provider.Name
 }
 
 // Title returns the dialog title
@@ -100,7 +137,21 @@ func (m EditDialogModel) Canceled() bool {
 // GetProvider returns the updated provider
 func (m EditDialogModel) GetProvider() provider.Provider {
 	p := m.provider
+	// Multiple keys are managed with 'ccm keys'
+	if len(p.Keys) > 0 {
+		p.BaseURL = m.fields[fieldBaseURL].Value()
+		p.Model = m.fields[fieldModel].Value()
+		return p
+	}
 	p.APIKey = m.fields[fieldAPIKey].Value()
+	// A typed key replaces the command or file source
+	if p.APIKey != "" {
+		p.APIKeyCmd, p.APIKeyFile = "", ""
+	}
+	// A new key restarts the rotation clock; the old expiry no longer applies
+	if p.APIKey != m.provider.APIKey {
+		p.KeyMeta.CreatedAt, p.KeyMeta.ExpiresAt = time.Now().Truncate(time.Second), time.Time{}
+	}
 	p.BaseURL = m.fields[fieldBaseURL].Value()
 	p.Model = m.fields[fieldModel].Value()
 	return p
@@ -128,7 +179,7 @@ func (m EditDialogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, textinput.Blink
 		case "enter":
 			// Validate
-			if m.fields[fieldAPIKey].Value() == "" {
+			if m.fields[fieldAPIKey].Value() == "" && m.provider.APIKeyCmd == "" && m.provider.APIKeyFile == "" && len(m.provider.Keys) == 0 {
 				return m, nil
 			}
 			m.submitted = true
@@ -194,13 +245,39 @@ func (m EditDialogModel) View() string {
 		b.WriteString("\n")
 	}
 
+	// Model suggestions
+	b.WriteString(m.suggestionsView())
+	b.WriteString("\n")
+
 	b.WriteString("\n")
 
 	// Buttons hint
-	buttonHint := styles.Muted.Render("Tab: next field  Enter: save  Esc: cancel")
+	buttonHint := styles.Muted.Render("Tab: next field  →: complete  Enter: save  Esc: cancel")
 	b.WriteString(buttonHint)
 
 	// Wrap in dialog box
 	content := b.String()
 	return styles.Dialog.Width(m.width).Render(content)
 }
+
+// suggestionsView shows the current model suggestion while the model field is focused
+func (m EditDialogModel) suggestionsView() string {
+	styles := theme.GetStyles()
+	field := m.fields[fieldModel]
+	if m.focusedField != fieldModel {
+		return ""
+	}
+
+	matched := field.MatchedSuggestions()
+	if len(matched) == 0 || (len(matched) == 1 && matched[0] == field.Value()) {
+		if n := len(field.AvailableSuggestions()); n > 0 {
+			return styles.Muted.Render(fmt.Sprintf("%d known models, type to complete", n))
+		}
+		return ""
+	}
+
+	current := field.CurrentSuggestionIndex()
+	return styles.Muted.Render("→ ") +
+		styles.Selected.Render(matched[current]) +
+		styles.Muted.Render(fmt.Sprintf("  (%d/%d, ^n/^p)", current+1, len(matched)))
+}
@@ -20,7 +20,7 @@ type HelpDialogModel struct {
 func NewHelpDialog() HelpDialogModel {
 	return HelpDialogModel{
 		width:  60,
-		height: 20,
+		height: 23,
 	}
 }
 
@@ -109,6 +109,7 @@ func (m HelpDialogModel) View() string {
 		{"e", "Edit provider configuration"},
 		{"d", "Set as default provider"},
 		{"t", "Test provider connection"},
+		{"T", "Test all configured providers"},
 		{"r", "Remove provider"},
 		{"a", "Add/configure provider"},
 	}
@@ -138,6 +139,8 @@ func (m HelpDialogModel) View() string {
 	}
 
 	b.WriteString("\n")
+	b.WriteString(styles.Muted.Render("Badges (ccm test --deep): S stream  T tools  P system  L long ctx"))
+	b.WriteString("\n\n")
 	b.WriteString(styles.Muted.Render("Press any key to close"))
 
 	content := b.String()
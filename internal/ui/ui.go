@@ -23,6 +23,10 @@ type TUIResult struct {
 
 // RunTUI launches the full-screen TUI and returns the result
 func RunTUI() (*TUIResult, error) {
+	// api_key_cmd must not read from the terminal while the TUI owns it
+	config.SetKeyCommandStdin(false)
+	defer config.SetKeyCommandStdin(true)
+
 	m := app.NewApp()
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
@@ -139,6 +143,22 @@ func SelectAction(actions []string, label string) (int, error) {
 	return result.Selected(), nil
 }
 
+// SelectItem shows a filterable list and returns the selected item
+func SelectItem(items []string, current, label string) (string, error) {
+	m := components.NewPicker(items, current, label)
+	p := tea.NewProgram(m)
+	finalModel, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+
+	result := finalModel.(components.PickerModel)
+	if result.Canceled() {
+		return "", ErrCanceled
+	}
+	return result.Selected(), nil
+}
+
 // BuildProviderItems builds provider list items from config
 func BuildProviderItems(cfg *config.Config, includeUnconfigured bool) []ProviderItem {
 	items := []ProviderItem{}
@@ -150,7 +170,7 @@ func BuildProviderItems(cfg *config.Config, includeUnconfigured bool) []Provider
 		isDefault := false
 
 		// Check if configured
-		if cp, exists := cfg.Providers[name]; exists && cp.APIKey != "" {
+		if cp, exists := cfg.Providers[name]; exists && cp.HasAPIKey() {
 			isConfigured = true
 		}
 
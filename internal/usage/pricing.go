@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"time"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+)
+
+// ModelPrices 内置的模型价格表 (USD)，按模型名前缀匹配（最长前缀优先）
+// 供应商未配置单价时使用
+var ModelPrices = map[string]provider.Pricing{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+}
+
+// LookupPrice 查找内置的模型价格
+func LookupPrice(model string) (provider.Pricing, bool) {
+	return provider.MatchPrice(ModelPrices, model)
+}
+
+// Pricer 计算调用费用，并换算为统一的货币
+type Pricer struct {
+	cfg *config.Config
+}
+
+// NewPricer 创建费用计算器，使用配置中的供应商单价、货币和汇率
+func NewPricer(cfg *config.Config) *Pricer {
+	return &Pricer{cfg: cfg}
+}
+
+// Currency 返回费用使用的货币
+func (p *Pricer) Currency() string {
+	return p.cfg.BudgetCurrency()
+}
+
+// Cost 计算一次调用的费用，没有价格或汇率时 ok 为 false
+// 优先使用供应商按模型配置的单价，其次为供应商的默认单价，最后按模型名查内置价格表
+func (p *Pricer) Cost(e Entry) (float64, bool) {
+	pricing, ok := p.pricing(e)
+	if !ok {
+		return 0, false
+	}
+	amount := pricing.Cost(e.Input, e.Output, e.CacheWrite, e.CacheRead)
+	converted, err := p.cfg.Convert(amount, pricing.EffectiveCurrency(), p.Currency())
+	if err != nil {
+		return 0, false
+	}
+	return converted, true
+}
+
+func (p *Pricer) pricing(e Entry) (provider.Pricing, bool) {
+	prov, ok := p.cfg.Providers[e.Provider]
+	if !ok {
+		prov, ok = provider.Presets[e.Provider]
+	}
+	if ok {
+		if pricing, ok := prov.ModelPricing(e.Model); ok {
+			return pricing, true
+		}
+	}
+	return LookupPrice(e.Model)
+}
+
+// Spend 供应商当天和当月的费用
+type Spend struct {
+	Today float64
+	Month float64
+}
+
+// ProviderSpend 统计供应商当天和当月的费用（以配置的货币表示）
+func ProviderSpend(cfg *config.Config, name string) (Spend, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	entries, err := Scan(name, monthStart)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	pricer := NewPricer(cfg)
+	var spend Spend
+	for _, e := range entries {
+		cost, ok := pricer.Cost(e)
+		if !ok {
+			continue
+		}
+		spend.Month += cost
+		if !e.Time.Before(dayStart) {
+			spend.Today += cost
+		}
+	}
+	return spend, nil
+}
@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"testing"
+
+	"ccm/internal/config"
+	"ccm/internal/provider"
+)
+
+func TestPricerPricing(t *testing.T) {
+	cfg := &config.Config{Providers: map[string]provider.Provider{
+		"deepseek": {
+			Name:    "deepseek",
+			Pricing: &provider.Pricing{Input: 2, Output: 3, Currency: "CNY"},
+			ModelPrices: map[string]provider.Pricing{
+				"deepseek-reasoner":    {Input: 4, Output: 16, Currency: "CNY"},
+				"deepseek-reasoner-v2": {Input: 5, Output: 20, Currency: "CNY"},
+			},
+		},
+		"relay": {
+			Name:        "relay",
+			ModelPrices: map[string]provider.Pricing{"claude-opus-4-5": {Input: 30, Output: 150, Currency: "CNY"}},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		want     float64 // 输入单价
+		wantOK   bool
+	}{
+		{name: "model price", provider: "deepseek", model: "deepseek-reasoner", want: 4, wantOK: true},
+		{name: "longest prefix", provider: "deepseek", model: "deepseek-reasoner-v2-0901", want: 5, wantOK: true},
+		{name: "provider price", provider: "deepseek", model: "deepseek-chat", want: 2, wantOK: true},
+		{name: "model price over built-in", provider: "relay", model: "claude-opus-4-5-20251101", want: 30, wantOK: true},
+		{name: "built-in", provider: "relay", model: "claude-sonnet-4-5-20250929", want: 3, wantOK: true},
+		{name: "preset price", provider: "qwen", model: "qwen3-coder-plus", want: provider.Presets["qwen"].Pricing.Input, wantOK: true},
+		{name: "unknown", provider: "relay", model: "unknown-model"},
+	}
+	pricer := NewPricer(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pricer.pricing(Entry{Provider: tt.provider, Model: tt.model})
+			if ok != tt.wantOK || got.Input != tt.want {
+				t.Errorf("pricing = %+v, %v, want input %g, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ccm/internal/config"
+)
+
+// Entry 一次 API 调用的 token 用量
+type Entry struct {
+	Provider   string
+	Project    string
+	Model      string
+	Time       time.Time
+	Input      int64 // 未命中缓存的输入 token
+	Output     int64
+	CacheWrite int64 // 写入缓存的输入 token
+	CacheRead  int64 // 命中缓存的输入 token
+}
+
+// transcriptLine Claude Code 会话记录中的一行（只解析需要的字段）
+type transcriptLine struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Cwd       string    `json:"cwd"`
+	RequestID string    `json:"requestId"`
+	Message   struct {
+		ID    string `json:"id"`
+		Model string `json:"model"`
+		Usage *struct {
+			InputTokens              int64 `json:"input_tokens"`
+			OutputTokens             int64 `json:"output_tokens"`
+			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+}
+
+// Scan 读取供应商配置目录下的所有会话记录，返回 since 之后的用量
+// since 为零值时返回全部记录
+func Scan(name string, since time.Time) ([]Entry, error) {
+	root := filepath.Join(config.GetClaudeConfigDir(name), "projects")
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var entries []Entry
+	seen := map[string]bool{}
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".jsonl") {
+			return nil
+		}
+		if !since.IsZero() {
+			// 最后修改时间早于起始时间的文件不可能包含新记录
+			if info, err := d.Info(); err == nil && info.ModTime().Before(since) {
+				return nil
+			}
+		}
+
+		project := filepath.Base(filepath.Dir(path))
+		found, err := parseTranscript(path, name, project, since, seen)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, found...)
+		return nil
+	})
+	return entries, err
+}
+
+// parseTranscript 解析单个会话记录文件
+// 流式响应会为同一条消息写入多行，使用消息 ID 和请求 ID 去重
+func parseTranscript(path, provider, project string, since time.Time, seen map[string]bool) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line transcriptLine
+		if json.Unmarshal(scanner.Bytes(), &line) != nil {
+			continue
+		}
+		if line.Type != "assistant" || line.Message.Usage == nil {
+			continue
+		}
+		if !since.IsZero() && line.Timestamp.Before(since) {
+			continue
+		}
+
+		key := line.Message.ID + ":" + line.RequestID
+		if line.Message.ID != "" {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+
+		if line.Cwd != "" {
+			project = line.Cwd
+		}
+		u := line.Message.Usage
+		entries = append(entries, Entry{
+			Provider:   provider,
+			Project:    project,
+			Model:      line.Message.Model,
+			Time:       line.Timestamp,
+			Input:      u.InputTokens,
+			Output:     u.OutputTokens,
+			CacheWrite: u.CacheCreationInputTokens,
+			CacheRead:  u.CacheReadInputTokens,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// Totals 一组调用的用量汇总
+type Totals struct {
+	Requests   int
+	Input      int64
+	Output     int64
+	CacheWrite int64
+	CacheRead  int64
+	Cost       float64 // 已知价格部分的费用（以 Pricer 的货币表示）
+	Unpriced   int     // 没有价格信息的请求数
+}
+
+// Tokens 返回全部 token 数
+func (t Totals) Tokens() int64 {
+	return t.Input + t.Output + t.CacheWrite + t.CacheRead
+}
+
+// add 累加一次调用
+func (t *Totals) add(e Entry, pricer *Pricer) {
+	t.Requests++
+	t.Input += e.Input
+	t.Output += e.Output
+	t.CacheWrite += e.CacheWrite
+	t.CacheRead += e.CacheRead
+	if cost, ok := pricer.Cost(e); ok {
+		t.Cost += cost
+	} else {
+		t.Unpriced++
+	}
+}
+
+// Row 分组汇总的一行
+type Row struct {
+	Key string
+	Totals
+}
+
+// GroupBy 分组方式
+type GroupBy string
+
+const (
+	ByProvider GroupBy = "provider"
+	ByProject  GroupBy = "project"
+	ByDay      GroupBy = "day"
+	ByModel    GroupBy = "model"
+)
+
+// GroupKinds 支持的分组方式
+var GroupKinds = []GroupBy{ByProvider, ByProject, ByDay, ByModel}
+
+// key 返回调用在该分组方式下的键
+func (g GroupBy) key(e Entry) string {
+	switch g {
+	case ByProject:
+		return e.Project
+	case ByDay:
+		return e.Time.Local().Format("2006-01-02")
+	case ByModel:
+		if e.Model == "" {
+			return "(unknown)"
+		}
+		return e.Model
+	default:
+		return e.Provider
+	}
+}
+
+// Summarize 按分组汇总用量，返回各组和总计
+// 按天分组时按日期排序，其他方式按 token 总量从大到小排序
+func Summarize(entries []Entry, by GroupBy, pricer *Pricer) ([]Row, Totals) {
+	groups := map[string]*Totals{}
+	var total Totals
+	for _, e := range entries {
+		k := by.key(e)
+		if groups[k] == nil {
+			groups[k] = &Totals{}
+		}
+		groups[k].add(e, pricer)
+		total.add(e, pricer)
+	}
+
+	rows := make([]Row, 0, len(groups))
+	for k, t := range groups {
+		rows = append(rows, Row{Key: k, Totals: *t})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if by == ByDay || rows[i].Tokens() == rows[j].Tokens() {
+			return rows[i].Key < rows[j].Key
+		}
+		return rows[i].Tokens() > rows[j].Tokens()
+	})
+	return rows, total
+}
+
+// Providers 返回存在会话记录目录的供应商名称（包括已删除配置但保留记录的供应商）
+func Providers() []string {
+	matches, _ := filepath.Glob(filepath.Join(config.GetConfigDir(), ".claude-*"))
+
+	var names []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			names = append(names, strings.TrimPrefix(filepath.Base(m), ".claude-"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}